@@ -0,0 +1,118 @@
+package ezconf
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var validDotenvKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// utility struct for a variable read from a dotenv file
+type dotenvVar struct {
+	value string
+	file  string
+	line  int
+}
+
+// parses the passed in dotenv files, skipping any which don't exist. Variables in later files override the same
+// variables in earlier files.
+func parseDotenvFiles(files []string) (map[string]dotenvVar, error) {
+	vars := make(map[string]dotenvVar)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		fileVars, err := parseDotenv(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		for k, v := range fileVars {
+			v.file = file
+			vars[k] = v
+		}
+	}
+
+	return vars, nil
+}
+
+// parses a dotenv document of KEY=value lines. Lines can be prefixed with export, values can be single quoted to be
+// taken literally or double quoted to allow escape sequences, and quoted values can span multiple lines. Comments
+// start with # and can follow unquoted values if preceded by whitespace.
+func parseDotenv(data string) (map[string]dotenvVar, error) {
+	vars := make(map[string]dotenvVar)
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		lineNum := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || !validDotenvKey.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid line, expected KEY=value", lineNum)
+		}
+		value = strings.TrimSpace(value)
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quote := value[0]
+			value = value[1:]
+
+			// keep adding lines until we find our closing quote
+			end := closingQuote(value, quote)
+			for end < 0 && i+1 < len(lines) {
+				i++
+				value += "\n" + lines[i]
+				end = closingQuote(value, quote)
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", lineNum)
+			}
+
+			rest := strings.TrimSpace(value[end+1:])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, fmt.Errorf("line %d: unexpected characters after quoted value", lineNum)
+			}
+
+			value = value[:end]
+			if quote == '"' {
+				value = unescapeDotenv(value)
+			}
+		} else if idx := strings.Index(value, " #"); idx >= 0 {
+			value = strings.TrimSpace(value[:idx])
+		}
+
+		vars[key] = dotenvVar{value: value, line: lineNum}
+	}
+
+	return vars, nil
+}
+
+// returns the index of the first unescaped quote in the passed in string, or -1 if there isn't one
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && quote == '"' {
+			i++
+		} else if s[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+var dotenvEscapes = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+
+func unescapeDotenv(s string) string {
+	return dotenvEscapes.Replace(s)
+}
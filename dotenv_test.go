@@ -0,0 +1,84 @@
+package ezconf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDotenv(t *testing.T) {
+	vars, err := parseDotenv(`
+# a comment
+FOO=bar
+export BAZ = qux # inline comment
+EMPTY=
+HASH=foo#bar
+DOUBLE="hello \"world\"\n\tdone" # comment
+SINGLE='literal \n $value'
+MULTI="line one
+line two"
+MULTI_SINGLE='one
+two'
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]dotenvVar{
+		"FOO":          {value: "bar", line: 3},
+		"BAZ":          {value: "qux", line: 4},
+		"EMPTY":        {value: "", line: 5},
+		"HASH":         {value: "foo#bar", line: 6},
+		"DOUBLE":       {value: "hello \"world\"\n\tdone", line: 7},
+		"SINGLE":       {value: `literal \n $value`, line: 8},
+		"MULTI":        {value: "line one\nline two", line: 9},
+		"MULTI_SINGLE": {value: "one\ntwo", line: 11},
+	}, vars)
+
+	_, err = parseDotenv("FOO=bar\nnot a variable\n")
+	assert.EqualError(t, err, "line 2: invalid line, expected KEY=value")
+
+	_, err = parseDotenv("FOO=\"bar\nBAZ=qux\n")
+	assert.EqualError(t, err, "line 1: unterminated quoted value")
+
+	_, err = parseDotenv("FOO='bar' baz\n")
+	assert.EqualError(t, err, "line 1: unexpected characters after quoted value")
+}
+
+func TestParseDotenvFiles(t *testing.T) {
+	vars, err := parseDotenvFiles([]string{"testdata/base.env", "testdata/missing.env", "testdata/local.env"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]dotenvVar{
+		"COURIER_NUM_WORKERS": {value: "16", file: "testdata/local.env", line: 1},
+		"COURIER_DB":          {value: "postgres://localhost/courier", file: "testdata/base.env", line: 3},
+		"COURIER_SECRET":      {value: "s3cr3t", file: "testdata/base.env", line: 4},
+		"COURIER_LABELS":      {value: "env=local", file: "testdata/local.env", line: 2},
+	}, vars)
+
+	_, err = parseDotenvFiles([]string{"testdata/simple.toml"})
+	assert.EqualError(t, err, "error parsing testdata/simple.toml: line 12: invalid line, expected KEY=value")
+}
+
+func TestEnvFiles(t *testing.T) {
+	type config struct {
+		NumWorkers int
+		DB         string
+		Secret     string
+		Labels     string
+	}
+
+	c := &config{NumWorkers: 2}
+	conf := NewLoader(c, "courier", "description", nil)
+	conf.SetEnvFiles("testdata/base.env", "testdata/local.env")
+	conf.SetArgs()
+	t.Setenv("COURIER_SECRET", "from-env")
+
+	// real environment variables override those in dotenv files, and later files override earlier ones
+	err := conf.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, &config{NumWorkers: 16, DB: "postgres://localhost/courier", Secret: "from-env", Labels: "env=local"}, c)
+	assert.Equal(t, []FieldSource{
+		{"db", "postgres://localhost/courier", Origin{Kind: OriginEnvFile, Name: "testdata/base.env", Line: 3}},
+		{"labels", "env=local", Origin{Kind: OriginEnvFile, Name: "testdata/local.env", Line: 2}},
+		{"num_workers", 16, Origin{Kind: OriginEnvFile, Name: "testdata/local.env", Line: 1}},
+		{"secret", "from-env", Origin{Kind: OriginEnv, Name: "COURIER_SECRET"}},
+	}, conf.Sources())
+	assert.Equal(t, "env file testdata/local.env:1", conf.Sources()[2].Origin.String())
+}
@@ -1,22 +1,193 @@
 package ezconf
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"slices"
 	"strings"
 	"time"
 )
 
-func parseEnv(name string, fields *ezFields) map[string]ezValue {
-	values := make(map[string]ezValue)
+// UnknownEnv controls what happens when environment variables with our prefix don't map to any field
+type UnknownEnv int
+
+const (
+	UnknownEnvIgnore UnknownEnv = iota // unknown variables are ignored
+	UnknownEnvWarn                     // unknown variables are logged as warnings
+	UnknownEnvError                    // unknown variables fail loading
+)
+
+// envSource is the Source for environment variables, including those read from any dotenv files
+type envSource struct {
+	name    string
+	env     map[string]string // used instead of the process environment if set
+	files   []string
+	fields  *ezFields
+	unknown UnknownEnv
+	log     *slog.Logger
+}
+
+func (s *envSource) Values(keys []string) (map[string]SourceValue, error) {
+	dotenv, err := parseDotenvFiles(s.files)
+	if err != nil {
+		return nil, err
+	}
+
+	env := s.env
+	if env == nil {
+		env = environ()
+	}
+
+	if s.unknown != UnknownEnvIgnore {
+		var errs []error
+		for _, u := range findUnknownEnv(s.name, s.fields, env, dotenv) {
+			if s.unknown == UnknownEnvError {
+				errs = append(errs, u.err())
+			} else {
+				attrs := []any{"var", u.env, "source", u.origin.String()}
+				if u.suggestion != "" {
+					attrs = append(attrs, "suggestion", u.suggestion)
+				}
+				s.log.Warn("unknown environment variable", attrs...)
+			}
+		}
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+	}
+
+	return parseEnv(s.name, s.fields, env, dotenv)
+}
+
+// returns the variables of the process environment
+func environ() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+	return env
+}
+
+// utility struct for a variable with our prefix which doesn't map to any field
+type unknownEnvVar struct {
+	env        string
+	origin     Origin
+	suggestion string // the variable that might have been intended, if any
+}
+
+func (u unknownEnvVar) err() error {
+	msg := "unknown environment variable " + u.env
+	if u.origin.Kind == OriginEnvFile {
+		msg += fmt.Sprintf(" in %s:%d", u.origin.Name, u.origin.Line)
+	}
+	if u.suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %s?", u.suggestion)
+	}
+	return errors.New(msg)
+}
+
+// finds every variable in the passed in environment and dotenv variables which has our prefix but doesn't map to
+// any field, along with a suggestion for the variable that might have been intended
+func findUnknownEnv(name string, fields *ezFields, env map[string]string, dotenv map[string]dotenvVar) []unknownEnvVar {
+	prefix := toEnvName(name, "")
+	known := make([]string, 0, len(fields.keys)*2)
+	for _, snake := range fields.keys {
+		known = append(known, toEnvName(name, snake))
+		if hasFileEnv(fields, snake) {
+			known = append(known, toEnvName(name, snake)+"_FILE")
+		}
+	}
+
+	var unknown []unknownEnvVar
+	check := func(env string, origin Origin) {
+		if strings.HasPrefix(env, prefix) && !slices.Contains(known, env) {
+			unknown = append(unknown, unknownEnvVar{env, origin, suggest(env, known)})
+		}
+	}
+
+	for _, k := range sortedKeys(env) {
+		check(k, Origin{Kind: OriginEnv, Name: k})
+	}
+	for _, k := range sortedKeys(dotenv) {
+		v := dotenv[k]
+		check(k, Origin{Kind: OriginEnvFile, Name: v.file, Line: v.line})
+	}
+
+	slices.SortStableFunc(unknown, func(a, b unknownEnvVar) int { return strings.Compare(a.env, b.env) })
+	return unknown
+}
+
+// reads values for our fields from the passed in environment variables, falling back to any variables read from
+// dotenv files. A value can also be read from a file by setting a variable with the _FILE suffix to its path, e.g.
+// COURIER_DB_FILE=/run/secrets/db, as is the convention for Docker secrets.
+func parseEnv(name string, fields *ezFields, env map[string]string, dotenv map[string]dotenvVar) (map[string]SourceValue, error) {
+	values := make(map[string]SourceValue)
+	var errs []error
+
+	lookups := []envLookup{
+		func(key string) (string, Origin) {
+			return env[key], Origin{Kind: OriginEnv, Name: key}
+		},
+		func(key string) (string, Origin) {
+			v := dotenv[key]
+			return v.value, Origin{Kind: OriginEnvFile, Name: v.file, Line: v.line}
+		},
+	}
+
 	for _, snake := range fields.keys {
-		env := strings.ToUpper(fmt.Sprintf("%s_%s", name, snake))
-		value := os.Getenv(env)
-		if value != "" {
-			values[snake] = ezValue{env, value}
+		envName := toEnvName(name, snake)
+		for _, lookup := range lookups {
+			value, origin, err := readEnv(envName, hasFileEnv(fields, snake), lookup)
+			if err != nil {
+				errs = append(errs, err)
+				break
+			}
+			if value != "" {
+				values[snake] = SourceValue{value, origin}
+				break
+			}
 		}
 	}
-	return values
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return values, nil
+}
+
+// looks up the value of a variable in a layer of environment variables, e.g. the real environment or dotenv files
+type envLookup func(key string) (string, Origin)
+
+// reads the value for the passed in variable from a single layer, returning an empty value if it isn't set
+func readEnv(env string, fileEnv bool, lookup envLookup) (string, Origin, error) {
+	value, origin := lookup(env)
+	if !fileEnv {
+		return value, origin, nil
+	}
+
+	path, fileOrigin := lookup(env + "_FILE")
+	if path == "" {
+		return value, origin, nil
+	}
+	if value != "" {
+		return "", Origin{}, fmt.Errorf("only one of %s and %s can be set", env, env+"_FILE")
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", Origin{}, fmt.Errorf("unable to read file for %s: %w", env+"_FILE", err)
+	}
+	return strings.TrimSpace(string(contents)), fileOrigin, nil
+}
+
+// returns whether the passed in field can be read from a file using a _FILE variable, which is the case unless
+// that would clash with the variable for another field, e.g. a field called log_file
+func hasFileEnv(fields *ezFields, snake string) bool {
+	_, clashes := fields.fields[snake+"_file"]
+	return !clashes
 }
 
 func buildEnvUsage(name string, fields *ezFields) string {
@@ -24,27 +195,45 @@ func buildEnvUsage(name string, fields *ezFields) string {
 	usage.WriteString("Environment variables:\n")
 
 	for _, snake := range fields.keys {
-		f := fields.fields[snake]
-
-		env := strings.ToUpper(fmt.Sprintf("%s_%s", name, snake))
-		switch f.Value().(type) {
-		case int, int8, int16, int32, int64:
-			fmt.Fprintf(&usage, "    % 40s - int\n", env)
-		case uint, uint8, uint16, uint32, uint64:
-			fmt.Fprintf(&usage, "    % 40s - uint\n", env)
-		case float32, float64:
-			fmt.Fprintf(&usage, "    % 40s - float\n", env)
-		case bool:
-			fmt.Fprintf(&usage, "    % 40s - bool\n", env)
-		case string:
-			fmt.Fprintf(&usage, "    % 40s - string\n", env)
-		case []int:
-			fmt.Fprintf(&usage, "    % 40s - comma separated integer list\n", env)
-		case []string:
-			fmt.Fprintf(&usage, "    % 40s - comma separated string list\n", env)
-		case time.Time:
-			fmt.Fprintf(&usage, "    % 40s - datetime\n", env)
+		typ := envType(fields.fields[snake])
+		env := toEnvName(name, snake)
+		fmt.Fprintf(&usage, "    % 40s - %s\n", env, typ)
+		if hasFileEnv(fields, snake) {
+			fmt.Fprintf(&usage, "    % 40s - path of file containing %s\n", env+"_FILE", typ)
 		}
 	}
 	return usage.String()
 }
+
+// returns a description of the type of value expected for the passed in field
+func envType(f *ezField) string {
+	switch f.Value().(type) {
+	case int, int8, int16, int32, int64:
+		return "int"
+	case uint, uint8, uint16, uint32, uint64:
+		return "uint"
+	case float32, float64:
+		return "float"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case []int:
+		return "comma separated integer list"
+	case []string:
+		return "comma separated string list"
+	case time.Time:
+		return "datetime"
+	case time.Duration:
+		return "duration"
+	}
+	if isTextType(f.value.Type()) {
+		return f.value.Type().String()
+	}
+	return ""
+}
+
+// returns the environment variable name for the passed in app name and snake_case field name, e.g. COURIER_NUM_WORKERS
+func toEnvName(name string, snake string) string {
+	return strings.ToUpper(fmt.Sprintf("%s_%s", name, snake))
+}
@@ -1,7 +1,10 @@
 package ezconf
 
 import (
+	"bytes"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"unicode"
@@ -21,9 +24,9 @@ func TestParseEnv(t *testing.T) {
 	tests := []struct {
 		env      map[string]string
 		fields   *ezFields
-		expected map[string]ezValue
+		expected map[string]SourceValue
 	}{
-		{map[string]string{"FOO_MY_INT": "32", "FOO_IGNORE": "none"}, toFields(t, intStruct), map[string]ezValue{"my_int": {"FOO_MY_INT", "32"}}},
+		{map[string]string{"FOO_MY_INT": "32", "FOO_IGNORE": "none"}, toFields(t, intStruct), map[string]SourceValue{"my_int": {"32", Origin{Kind: OriginEnv, Name: "FOO_MY_INT"}}}},
 	}
 
 	for _, tc := range tests {
@@ -31,7 +34,8 @@ func TestParseEnv(t *testing.T) {
 			os.Setenv(k, v)
 		}
 
-		val := parseEnv("foo", tc.fields)
+		val, err := parseEnv("foo", tc.fields, environ(), nil)
+		assert.NoError(t, err)
 		assert.Equal(t, tc.expected, val, "parseEnv failed for env: %s", tc.env)
 
 		for k := range tc.env {
@@ -53,12 +57,104 @@ func TestBuildUsage(t *testing.T) {
 	fields := toFields(t, allKinds{})
 	expected := `Environment variables:
 				 FOO_MY_BOOL - bool
+            FOO_MY_BOOL_FILE - path of file containing bool
              FOO_MY_DATETIME - datetime
+        FOO_MY_DATETIME_FILE - path of file containing datetime
+             FOO_MY_DURATION - duration
+        FOO_MY_DURATION_FILE - path of file containing duration
                 FOO_MY_FLOAT - float
+           FOO_MY_FLOAT_FILE - path of file containing float
                   FOO_MY_INT - int
+             FOO_MY_INT_FILE - path of file containing int
                FOO_MY_STRING - string
-                 FOO_MY_UINT - uint`
+          FOO_MY_STRING_FILE - path of file containing string
+                 FOO_MY_UINT - uint
+            FOO_MY_UINT_FILE - path of file containing uint`
 	usage := buildEnvUsage("foo", fields)
 
 	assert.Equal(t, stripWhitespace(expected), stripWhitespace(usage))
 }
+
+func TestParseEnvFiles(t *testing.T) {
+	type config struct {
+		DB      string
+		APIKey  string
+		Log     string
+		LogFile string
+	}
+
+	fields := toFields(t, &config{})
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "db"), []byte("postgres://secret\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "empty"), []byte("  \n"), 0600)
+
+	setEnv := func(env map[string]string) {
+		for k, v := range env {
+			t.Setenv(k, v)
+		}
+	}
+
+	// values are read from files and trimmed
+	setEnv(map[string]string{"FOO_DB_FILE": filepath.Join(dir, "db"), "FOO_API_KEY_FILE": filepath.Join(dir, "empty"), "FOO_LOG_FILE": "/var/log/foo.log"})
+	values, err := parseEnv("foo", fields, environ(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]SourceValue{
+		"db":       {"postgres://secret", Origin{Kind: OriginEnv, Name: "FOO_DB_FILE"}},
+		"log_file": {"/var/log/foo.log", Origin{Kind: OriginEnv, Name: "FOO_LOG_FILE"}},
+	}, values)
+
+	// can't set both forms or use a file that doesn't exist
+	setEnv(map[string]string{"FOO_DB": "postgres://env", "FOO_API_KEY_FILE": filepath.Join(dir, "missing")})
+	_, err = parseEnv("foo", fields, environ(), nil)
+	assert.EqualError(t, err, "unable to read file for FOO_API_KEY_FILE: open "+filepath.Join(dir, "missing")+": no such file or directory\n"+
+		"only one of FOO_DB and FOO_DB_FILE can be set")
+
+	// no _FILE variant is listed for fields where it would clash with another field
+	usage := buildEnvUsage("foo", fields)
+	assert.Contains(t, usage, "FOO_DB_FILE - path of file containing string")
+	assert.NotContains(t, usage, "FOO_LOG_FILE - path of file containing string")
+	assert.Contains(t, usage, "FOO_LOG_FILE - string")
+}
+
+func TestUnknownEnv(t *testing.T) {
+	type config struct {
+		NumWorkers int
+		DB         string
+	}
+
+	t.Setenv("COURIER_NUM_WORKES", "64")
+	t.Setenv("COURIER_DB_FILE", "testdata/base.env")
+	t.Setenv("COURIER_UNRELATED", "foo")
+	t.Setenv("COURIERX_NUM_WORKERS", "12")
+
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, ".env")
+	os.WriteFile(envFile, []byte("COURIER_NUM_WORKERS=8\nCOURIER_BD=postgres://localhost\n"), 0600)
+
+	newLoader := func(unknown UnknownEnv, logs *bytes.Buffer) *Loader {
+		conf := NewLoader(&config{}, "courier", "description", nil)
+		conf.SetEnvFiles(envFile)
+		conf.SetUnknownEnv(unknown)
+		conf.SetLogger(slog.New(slog.NewTextHandler(logs, testLogOptions)))
+		conf.SetArgs()
+		return conf
+	}
+
+	// by default unknown variables are ignored
+	logs := &bytes.Buffer{}
+	assert.NoError(t, newLoader(UnknownEnvIgnore, logs).Load())
+	assert.Equal(t, "", logs.String())
+
+	// but can be logged as warnings
+	assert.NoError(t, newLoader(UnknownEnvWarn, logs).Load())
+	assert.Equal(t, `level=WARN msg="unknown environment variable" var=COURIER_BD source="env file `+envFile+`:2" suggestion=COURIER_DB
+level=WARN msg="unknown environment variable" var=COURIER_NUM_WORKES source="env COURIER_NUM_WORKES" suggestion=COURIER_NUM_WORKERS
+level=WARN msg="unknown environment variable" var=COURIER_UNRELATED source="env COURIER_UNRELATED"
+`, logs.String())
+
+	// or fail loading
+	err := newLoader(UnknownEnvError, logs).Load()
+	assert.EqualError(t, err, "unknown environment variable COURIER_BD in "+envFile+":2, did you mean COURIER_DB?\n"+
+		"unknown environment variable COURIER_NUM_WORKES, did you mean COURIER_NUM_WORKERS?\n"+
+		"unknown environment variable COURIER_UNRELATED")
+}
@@ -1,15 +1,22 @@
 package ezconf
 
 import (
+	"cmp"
+	"encoding"
 	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"reflect"
 	"regexp"
-	"sort"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fatih/structs"
@@ -17,350 +24,723 @@ import (
 
 var validNameTag = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
 
+// ErrHelp is returned by Load if usage information was requested with -help or -h
+var ErrHelp = flag.ErrHelp
+
+// ErrGenConf is returned by Load if a sample config file was requested with -gen-conf, which can be generated with
+// GenerateTOML
+var ErrGenConf = errors.New("sample config file requested")
+
 // Loader allows you to load your configuration from four sources, in order of priority (later overrides earlier):
 //  1. The default values of your configuration struct
-//  2. TOML files you specify (optional)
-//  3. Set environment variables
+//  2. TOML, YAML or JSON files you specify (optional)
+//  3. Set environment variables, or variables in dotenv files you specify (optional)
 //  4. Command line parameters
+//
+// Custom sources can be added at any priority between these with AddSource.
+//
+// Fields tagged with `required:"true"` must be set by one of the last three sources or loading will fail. Loading
+// will also fail if any final value breaks the rules set by the `min`, `max`, `oneof` or `pattern` tags of its field,
+// or if your configuration struct implements Validator and returns an error.
 type Loader struct {
 	name        string
 	description string
 	config      any
 	files       []string
+	mergeFiles  bool
+	strict      bool
+	envFiles    []string
+	unknownEnv  UnknownEnv
+	env         map[string]string
 	args        []string
+	sources     []prioritizedSource
+	output      io.Writer
+	logger      *slog.Logger
 
 	// we hang onto this to print usage where needed
 	flags *flag.FlagSet
+
+	// the defaults of our config which reloads start from, and our current config after loading
+	defaults any
+	state    atomic.Pointer[loadState]
+	reloadMu sync.Mutex
+	onChange []ChangeFunc
+	holders  []func(config any)
+}
+
+// utility struct for a loaded config along with its fields and where each of their values came from
+type loadState struct {
+	config  any
+	fields  *ezFields
+	origins map[string]Origin
 }
 
 // NewLoader creates a new EZLoader for the passed in configuration. `config` should be a pointer to a struct.
 // `name` and `description` are used to build environment variables and help parameters. The list of files
-// can be nil, or can contain optional files to read TOML configuration from in priority order. The first file
-// found and parsed will end parsing of others, but there is no requirement that any file is found.
+// can be nil, or can contain optional files to read TOML configuration from in priority order. Files with a .yaml
+// or .yml extension are read as YAML, and files with a .json extension as JSON, using the same key names as TOML. The first file
+// found and parsed will end parsing of others unless merging is enabled with SetMergeFiles, but there is no
+// requirement that any file is found.
 func NewLoader(config any, name string, description string, files []string) *Loader {
-	return &Loader{
-		name:        name,
-		description: description,
-		config:      config,
-		files:       files,
-		args:        os.Args[1:],
+	return newLoader(config, WithName(name), WithDescription(description), WithFiles(files...))
+}
+
+func newLoader(config any, opts ...Option) *Loader {
+	l := &Loader{
+		config: config,
+		args:   os.Args[1:],
+		logger: slog.New(newConfHandler(os.Stdout)),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Load loads a config of type T, which should be a struct, starting from a copy of the passed in defaults, from the
+// sources configured by the passed in options, which must include a name set with WithName, e.g.
+//
+//	config, err := ezconf.Load(Config{NumWorkers: 4}, ezconf.WithName("courier"), ezconf.WithFiles("courier.toml"))
+//
+// If usage information or a sample config file was requested, it is written before returning ErrHelp or ErrGenConf
+// so that the caller only needs to exit. Use NewLoader instead for configs that need to be reloaded.
+func Load[T any](defaults T, opts ...Option) (*T, error) {
+	if typ := reflect.TypeFor[T](); typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config must be a struct, not %s", typ)
+	}
+
+	config := copyConfig(&defaults)
+	l := newLoader(config, opts...)
+	if l.name == "" {
+		return nil, errors.New("name must be set with WithName as it is used for environment variables and help")
+	}
+
+	err := l.Load()
+	if err == ErrHelp {
+		l.Usage()
+	} else if err == ErrGenConf {
+		sample, err := GenerateTOML(config)
+		if err != nil {
+			return nil, err
+		}
+		os.Stdout.Write(sample)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// SetMergeFiles controls whether all found files are read. By default only the first file found is read, but
+// if merging is enabled then every found file is read in order, with keys in later files overriding the same keys,
+// including those inside nested tables, in earlier files.
+func (l *Loader) SetMergeFiles(merge bool) {
+	l.mergeFiles = merge
+}
+
+// SetStrict controls whether files are checked for keys which don't map to any field of your configuration struct.
+// If enabled, loading fails with an error reporting the file and line of every unknown key, and suggesting the key
+// that might have been intended, e.g. "courier.toml:3: unknown key num_worker, did you mean num_workers?".
+func (l *Loader) SetStrict(strict bool) {
+	l.strict = strict
+}
+
+// SetEnvFiles sets optional dotenv files to read environment variables from, e.g. ".env". Variables in later files
+// override the same variables in earlier files, and real environment variables override them all. Missing files are
+// skipped.
+func (l *Loader) SetEnvFiles(files ...string) {
+	l.envFiles = files
+}
+
+// AddSource adds a custom source of values with the passed in priority relative to the built-in sources, e.g.
+// a priority between PriorityFiles and PriorityEnv means values from the source override those in files but are
+// overridden by environment variables. Sources with the same priority are applied in the order they were added,
+// after any built-in source with that priority.
+func (l *Loader) AddSource(source Source, priority int) {
+	l.sources = append(l.sources, prioritizedSource{source, priority})
+}
+
+// Usage writes usage information for the command line parameters and environment variables of the last load.
+func (l *Loader) Usage() {
+	if l.flags != nil {
+		l.flags.Usage()
 	}
 }
 
+// SetOutput sets where usage information and errors from MustLoad are written. By default usage information is
+// written to stderr and errors to stdout.
+func (l *Loader) SetOutput(w io.Writer) {
+	l.output = w
+}
+
+// SetLogger sets the logger used for the output of -debug-conf, which by default writes lines prefixed with CONF: to
+// stdout. Each value is logged with field, value and source attributes.
+func (l *Loader) SetLogger(logger *slog.Logger) {
+	l.logger = logger
+}
+
+// SetUnknownEnv controls what happens when set environment variables, or variables in dotenv files, have the prefix
+// of your app name but don't map to any field, e.g. COURIER_NUM_WORKES. By default they are ignored, but they can
+// instead be logged as warnings, or fail loading, with a suggestion for the variable that might have been intended.
+func (l *Loader) SetUnknownEnv(unknown UnknownEnv) {
+	l.unknownEnv = unknown
+}
+
 // SetArgs allows you to override the command line arguments to be parsed. This is primarily useful for tests.
 func (l *Loader) SetArgs(args ...string) {
 	l.args = args
 }
 
+// SetEnv allows you to override the environment variables to be read, which are otherwise read from the process
+// environment. This is primarily useful for tests.
+func (l *Loader) SetEnv(env map[string]string) {
+	l.env = env
+}
+
 // MustLoad loads our configuration from our sources in the order of:
-//  1. TOML files
+//  1. TOML, YAML or JSON files
 //  2. Environment variables
 //  3. Command line parameters
 //
-// If any error is encountered, the program will exit reporting the error and showing usage.
+// If usage information is requested, the program will exit after showing it, and if a sample config file is
+// requested, the program will exit after writing it to stdout. If any error is encountered, the program will exit
+// reporting the error and showing usage.
 func (l *Loader) MustLoad() {
+	output := l.output
+	if output == nil {
+		output = os.Stdout
+	}
+
 	err := l.Load()
+	if err == ErrGenConf {
+		sample, err := GenerateTOML(l.config)
+		if err != nil {
+			fmt.Fprintf(output, "Error while generating sample configuration: %s\n", err.Error())
+			os.Exit(1)
+		}
+		os.Stdout.Write(sample)
+		os.Exit(0)
+	}
 	if err != nil {
-		fmt.Printf("Error while reading configuration: %s\n\n", err.Error())
-		l.flags.Usage()
+		if err != ErrHelp {
+			fmt.Fprintf(output, "Error while reading configuration: %s\n\n", err.Error())
+		}
+		l.Usage()
 		os.Exit(1)
 	}
 }
 
 // Load loads our configuration from our sources in the order of:
-//  1. TOML files
+//  1. TOML, YAML or JSON files
 //  2. Environment variables
 //  3. Command line parameters
 //
-// If any error is encountered it is returned for the caller to process.
+// If any error is encountered it is returned for the caller to process. If usage information was requested then
+// ErrHelp is returned without loading anything, and the caller can show usage with Usage. Similarly if a sample
+// config file was requested then ErrGenConf is returned.
 func (l *Loader) Load() error {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+
+	// hang onto our defaults so that reloads can start from them
+	if l.defaults == nil {
+		l.defaults = deepCopy(reflect.ValueOf(l.config)).Interface()
+	}
+
+	state, err := l.load(l.config)
+	if state != nil {
+		l.state.Store(state)
+	}
+
+	// holders get a copy of our config that can't be modified through the pointer we were given
+	if err == nil && len(l.holders) > 0 {
+		snapshot := deepCopy(reflect.ValueOf(l.config)).Interface()
+		for _, hold := range l.holders {
+			hold(snapshot)
+		}
+	}
+	return err
+}
+
+// loads our sources into the passed in config, returning the resulting state once our fields are built
+func (l *Loader) load(config any) (*loadState, error) {
+	// we can only load into a struct we have a pointer to
+	if v := reflect.ValueOf(config); v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config must be a non-nil pointer to a struct, not %T", config)
+	}
+
 	// first build our mapping of name snake_case -> structs.Field
-	fields, err := buildFields(l.config)
+	fields, err := buildFields(config)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// build our flags
-	l.flags = buildFlags(l.name, l.description, fields, flag.ExitOnError)
+	l.flags = buildFlags(l.name, l.description, fields, flag.ContinueOnError)
 
-	// parse them
-	flagValues, err := parseFlags(l.flags, l.args)
+	// parse them, errors are returned rather than written to output along with usage
+	l.flags.SetOutput(io.Discard)
+	err = l.flags.Parse(l.args)
+	l.flags.SetOutput(l.output)
 	if err != nil {
-		return err
+		return nil, redactFlagError(err, fields)
 	}
 
-	// if they asked for usage, show it
+	// if they asked for usage, let the caller show it
 	if l.flags.Lookup("help").Value.String() == "true" {
-		l.flags.Usage()
-		os.Exit(1)
+		return nil, ErrHelp
 	}
 
-	// if they asked for config debug, show it
-	debug := false
-	if l.flags.Lookup("debug-conf").Value.String() == "true" {
-		debug = true
+	// likewise if they asked for a sample config file, which should use our defaults
+	if l.flags.Lookup("gen-conf").Value.String() == "true" {
+		return nil, ErrGenConf
 	}
 
-	if debug {
-		printFields("Default overridable values:", fields)
+	// if they asked for config debug, log it, otherwise debug output is discarded
+	log := slog.New(slog.DiscardHandler)
+	if l.flags.Lookup("debug-conf").Value.String() == "true" {
+		log = l.logger
 	}
+	logFields(log, "default value", fields, nil)
 
-	// read any found file into our config
-	err = parseTOMLFiles(l.config, l.files, debug)
-	if err != nil {
-		return err
+	// every field starts off with its default value
+	state := &loadState{config: config, fields: fields, origins: make(map[string]Origin, len(fields.keys))}
+	for _, k := range fields.keys {
+		state.origins[k] = Origin{Kind: OriginDefault}
 	}
 
-	if debug {
-		printFields("Overridable values after TOML parsing:", fields)
+	// apply the values from each of our sources in order of priority
+	sources := []prioritizedSource{
+		{&fileSource{config, l.files, l.mergeFiles, l.strict, log, fields}, PriorityFiles},
+		{&envSource{l.name, l.env, l.envFiles, fields, l.unknownEnv, l.logger}, PriorityEnv},
+		{&flagSource{l.flags}, PriorityFlags},
 	}
+	sources = append(sources, l.sources...)
+	slices.SortStableFunc(sources, func(a, b prioritizedSource) int { return cmp.Compare(a.priority, b.priority) })
 
-	// parse our environment
-	envValues := parseEnv(l.name, fields)
-	err = setValues(fields, envValues)
-	if err != nil {
-		return err
+	for _, s := range sources {
+		values, err := s.source.Values(fields.keys)
+		if err != nil {
+			return state, err
+		}
+		err = setValues(fields, values)
+		if err != nil {
+			return state, err
+		}
+		for k, v := range values {
+			state.origins[k] = v.Origin
+		}
+
+		logValues(log, "value overridden", fields, values)
 	}
+	logFields(log, "final value", fields, state.origins)
 
-	// set our flag values
-	err = setValues(fields, flagValues)
-	if err != nil {
-		return err
+	// check that every required field was set by one of our sources and that every value is valid
+	var errs []error
+	for _, k := range fields.keys {
+		f := fields.fields[k]
+		if f.Tag("required") == "true" && state.origins[k].Kind == OriginDefault {
+			errs = append(errs, fmt.Errorf("missing required value for %s, set it in a config file, with %s or with -%s", k, toEnvName(l.name, k), toFlagName(k)))
+		}
+		errs = append(errs, validateField(k, f, state.origins[k])...)
 	}
 
-	if debug {
-		printValues("Command line overrides:", flagValues)
-		printValues("Environment overrides:", envValues)
-		printFields("Final top level values:", fields)
+	// finally let our config and any nested structs validate themselves
+	errs = append(errs, runValidators(reflect.Indirect(reflect.ValueOf(config)))...)
+
+	return state, errors.Join(errs...)
+}
+
+// Sources returns the final value of each field after loading along with where that value came from, ordered by
+// the snake_case names of the fields. Values of secret fields and passwords in URLs are redacted, in which case the
+// value will be a string. Returns nil if the configuration hasn't been loaded.
+func (l *Loader) Sources() []FieldSource {
+	state := l.state.Load()
+	if state == nil {
+		return nil
 	}
 
-	return nil
+	sources := make([]FieldSource, len(state.fields.keys))
+	for i, k := range state.fields.keys {
+		f := state.fields.fields[k]
+		var value any = f.Value()
+		if display, wasRedacted := redactValue(f, formatValue(f)); wasRedacted {
+			value = display
+		}
+		sources[i] = FieldSource{Field: k, Value: value, Origin: state.origins[k]}
+	}
+	return sources
 }
 
-func setValues(fields *ezFields, values map[string]ezValue) error {
+func setValues(fields *ezFields, values map[string]SourceValue) error {
 	// iterates all passed in values, attempting to set them, returning an error if
 	// there are any type mismatches
 	for name, cValue := range values {
-		value := cValue.value
-
 		f, found := fields.fields[name]
 		if !found {
-			return fmt.Errorf("unknown key '%s' for value '%s'", name, value)
+			return fmt.Errorf("unknown key '%s' from %s", name, cValue.Origin)
 		}
 
-		switch f.Value().(type) {
-		case int:
-			i, err := strconv.ParseInt(value, 10, strconv.IntSize)
-			if err != nil {
-				return err
-			}
-			f.Set(int(i))
-		case int8:
-			i, err := strconv.ParseInt(value, 10, 8)
-			if err != nil {
-				return err
+		// values which aren't strings must already be of the field's type
+		value, isString := cValue.Value.(string)
+		if !isString {
+			v := reflect.ValueOf(cValue.Value)
+			if !v.IsValid() || !v.Type().AssignableTo(f.value.Type()) {
+				display, _ := redactValue(f, fmt.Sprint(cValue.Value))
+				return fmt.Errorf("invalid value '%s' for %s, must be a string or %s", display, name, f.value.Type())
 			}
-			f.Set(int8(i))
-		case int16:
-			i, err := strconv.ParseInt(value, 10, 16)
-			if err != nil {
-				return err
-			}
-			f.Set(int16(i))
-		case int32:
-			i, err := strconv.ParseInt(value, 10, 32)
-			if err != nil {
-				return err
-			}
-			f.Set(int32(i))
-		case int64:
-			i, err := strconv.ParseInt(value, 10, 64)
-			if err != nil {
-				return err
-			}
-			f.Set(int64(i))
+			f.value.Set(v)
+			continue
+		}
 
-		case uint:
-			i, err := strconv.ParseUint(value, 10, strconv.IntSize)
-			if err != nil {
-				return err
-			}
-			f.Set(uint(i))
+		if err := setValue(f, value); err != nil {
+			return invalidValueError(name, f, value, cValue.Origin, err)
+		}
+	}
+	return nil
+}
 
-		case uint8:
-			i, err := strconv.ParseUint(value, 10, 8)
-			if err != nil {
-				return err
-			}
-			f.Set(uint8(i))
-		case uint16:
-			i, err := strconv.ParseUint(value, 10, 16)
-			if err != nil {
-				return err
-			}
-			f.Set(uint16(i))
-		case uint32:
-			i, err := strconv.ParseUint(value, 10, 32)
-			if err != nil {
-				return err
-			}
-			f.Set(uint32(i))
-		case uint64:
-			i, err := strconv.ParseUint(value, 10, 64)
-			if err != nil {
-				return err
-			}
-			f.Set(uint64(i))
+// returns an error for a value which couldn't be parsed for a field, leaving out the details of the parse error,
+// which usually include the value, if the value needs to be redacted
+func invalidValueError(name string, f *ezField, value string, origin Origin, err error) error {
+	if display, wasRedacted := redactValue(f, value); wasRedacted {
+		return fmt.Errorf("invalid value %s for %s from %s", display, name, origin)
+	}
+	return fmt.Errorf("invalid value %s for %s from %s: %w", value, name, origin, err)
+}
 
-		case float32:
-			d, err := strconv.ParseFloat(value, 32)
-			if err != nil {
-				return err
-			}
-			f.Set(float32(d))
-		case float64:
-			d, err := strconv.ParseFloat(value, 32)
-			if err != nil {
-				return err
-			}
-			f.Set(float64(d))
+// sets the passed in field by parsing the passed in string as its type
+func setValue(f *ezField, value string) error {
+	switch f.Value().(type) {
+	case int:
+		i, err := strconv.ParseInt(value, 10, strconv.IntSize)
+		if err != nil {
+			return err
+		}
+		f.Set(int(i))
+	case int8:
+		i, err := strconv.ParseInt(value, 10, 8)
+		if err != nil {
+			return err
+		}
+		f.Set(int8(i))
+	case int16:
+		i, err := strconv.ParseInt(value, 10, 16)
+		if err != nil {
+			return err
+		}
+		f.Set(int16(i))
+	case int32:
+		i, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return err
+		}
+		f.Set(int32(i))
+	case int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.Set(int64(i))
 
-		case bool:
-			b, err := strconv.ParseBool(value)
-			if err != nil {
-				return err
-			}
-			f.Set(b)
+	case uint:
+		i, err := strconv.ParseUint(value, 10, strconv.IntSize)
+		if err != nil {
+			return err
+		}
+		f.Set(uint(i))
 
-		case string:
-			f.Set(value)
+	case uint8:
+		i, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return err
+		}
+		f.Set(uint8(i))
+	case uint16:
+		i, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return err
+		}
+		f.Set(uint16(i))
+	case uint32:
+		i, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return err
+		}
+		f.Set(uint32(i))
+	case uint64:
+		i, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.Set(uint64(i))
 
-		case []string:
-			parts, err := csv.NewReader(strings.NewReader(value)).Read()
-			if err != nil {
-				return err
-			}
-			for i, p := range parts {
-				parts[i] = strings.TrimSpace(p)
-			}
-			f.Set(parts)
+	case float32:
+		d, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return err
+		}
+		f.Set(float32(d))
+	case float64:
+		d, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return err
+		}
+		f.Set(float64(d))
+
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.Set(b)
 
-		case []int:
-			parts, err := csv.NewReader(strings.NewReader(value)).Read()
+	case string:
+		f.Set(value)
+
+	case []string:
+		parts, err := csv.NewReader(strings.NewReader(value)).Read()
+		if err != nil {
+			return err
+		}
+		for i, p := range parts {
+			parts[i] = strings.TrimSpace(p)
+		}
+		f.Set(parts)
+
+	case []int:
+		parts, err := csv.NewReader(strings.NewReader(value)).Read()
+		if err != nil {
+			return err
+		}
+		ints := make([]int, len(parts))
+		for i, p := range parts {
+			n, err := strconv.ParseInt(strings.TrimSpace(p), 10, strconv.IntSize)
 			if err != nil {
 				return err
 			}
-			ints := make([]int, len(parts))
-			for i, p := range parts {
-				n, err := strconv.ParseInt(strings.TrimSpace(p), 10, strconv.IntSize)
-				if err != nil {
-					return err
-				}
-				ints[i] = int(n)
+			ints[i] = int(n)
+		}
+		f.Set(ints)
+
+	case time.Time:
+		t, err := parseDatetime(value)
+		if err != nil {
+			return err
+		}
+		f.Set(t)
+
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		f.Set(d)
+
+	case slog.Level:
+		var level slog.Level
+		err := level.UnmarshalText([]byte(value))
+		if err != nil {
+			return err
+		}
+		f.Set(level)
+
+	default:
+		err := f.setText(value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buildFields(config any) (*ezFields, error) {
+	if v := reflect.Indirect(reflect.ValueOf(config)); v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config must be a struct or a pointer to a struct, not %T", config)
+	}
+
+	fields := make(map[string]*ezField)
+	err := addFields(fields, structs.New(config).Fields(), reflect.Indirect(reflect.ValueOf(config)), "", "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ezFields{sortedKeys(fields), fields}, nil
+}
+
+// recursively adds the supported fields in the passed in list to our map, nested structs have their
+// fields added with the snake_case name of the struct as a prefix, e.g. DB.PoolSize becomes db_pool_size. Fields
+// of structs tagged with `reload:"false"` can't be reloaded either.
+func addFields(fields map[string]*ezField, structFields []*structs.Field, structValue reflect.Value, prefix string, pathPrefix string, restartOnly bool) error {
+	for _, f := range structFields {
+		if !f.IsExported() {
+			continue
+		}
+
+		name := f.Tag("name")
+		if name == "" {
+			name = CamelToSnake(f.Name())
+		} else if !validNameTag.MatchString(name) {
+			return fmt.Errorf("invalid name tag %q for field %s, must be snake_case", name, pathPrefix+f.Name())
+		}
+		name = prefix + name
+		path := pathPrefix + f.Name()
+		value := structValue.FieldByName(f.Name())
+		fieldRestartOnly := restartOnly || f.Tag("reload") == "false"
+
+		if isSupportedType(value.Type()) {
+			dupe, found := fields[name]
+			if found {
+				return fmt.Errorf("%s name collides with %s", dupe.path, path)
 			}
-			f.Set(ints)
-
-		case time.Time:
-			var t time.Time
-			var err error
-
-			switch {
-			case !strings.Contains(value, ":"):
-				t, err = time.Parse("2006-01-02", value)
-			case !strings.Contains(value, "-"):
-				t, err = time.Parse("15:04:05.999999999", value)
-			default:
-				for _, format := range timeFormats {
-					t, err = time.Parse(format, value)
-					if err == nil {
-						break
-					}
-				}
+			if slices.Contains(builtinFlags, name) {
+				return fmt.Errorf("%s name %s is reserved for the -%s flag", path, name, toFlagName(name))
 			}
-
+			field := &ezField{Field: f, path: path, value: value, restartOnly: fieldRestartOnly}
+			rules, err := parseRules(field)
 			if err != nil {
 				return err
 			}
+			field.rules = rules
+			fields[name] = field
 
-			f.Set(t)
-
-		case slog.Level:
-			var level slog.Level
-			err := level.UnmarshalText([]byte(value))
+		} else if f.Kind() == reflect.Struct {
+			err := addFields(fields, f.Fields(), value, name+"_", path+".", fieldRestartOnly)
 			if err != nil {
 				return err
 			}
-			f.Set(level)
 		}
 	}
 	return nil
 }
 
-func buildFields(config any) (*ezFields, error) {
-	fields := make(map[string]*structs.Field)
-	s := structs.New(config)
-	for _, f := range s.Fields() {
-		if f.IsExported() {
-			switch f.Value().(type) {
-			case int, int8, int16, int32, int64,
-				uint, uint8, uint16, uint32, uint64,
-				float32, float64,
-				bool,
-				string,
-				[]string,
-				[]int,
-				time.Time,
-				slog.Level:
-				name := f.Tag("name")
-				if name == "" {
-					name = CamelToSnake(f.Name())
-				} else if !validNameTag.MatchString(name) {
-					return nil, fmt.Errorf("invalid name tag %q for field %s, must be snake_case", name, f.Name())
-				}
-				dupe, found := fields[name]
-				if found {
-					return nil, fmt.Errorf("%s name collides with %s", dupe.Name(), f.Name())
-				}
-				fields[name] = f
-			}
-		}
+// returns whether fields of the passed in type can be set from env vars and flags
+func isSupportedType(typ reflect.Type) bool {
+	switch reflect.Zero(typ).Interface().(type) {
+	case int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64,
+		bool,
+		string,
+		[]string,
+		[]int,
+		time.Time,
+		time.Duration,
+		slog.Level:
+		return true
+	}
+	return isTextType(typ)
+}
+
+// returns whether the passed in type is a custom type that can be parsed from a string, i.e. a pointer
+// to it implements encoding.TextUnmarshaler or flag.Value
+func isTextType(typ reflect.Type) bool {
+	ptr := reflect.PointerTo(typ)
+	return ptr.Implements(textUnmarshalerType) || ptr.Implements(flagValueType)
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+var flagValueType = reflect.TypeOf((*flag.Value)(nil)).Elem()
+
+// utility struct for a field we can set, along with its path from the root struct, e.g. DB.PoolSize
+type ezField struct {
+	*structs.Field
+	path        string
+	value       reflect.Value
+	rules       *ezRules
+	restartOnly bool // whether changes to the field can only be applied by restarting rather than reloading
+}
+
+// sets the value of a custom type field from a string using its encoding.TextUnmarshaler or flag.Value implementation
+func (f *ezField) setText(value string) error {
+	if !f.value.CanAddr() {
+		return fmt.Errorf("field %s is not settable", f.path)
+	}
+
+	switch v := f.value.Addr().Interface().(type) {
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText([]byte(value))
+	case flag.Value:
+		return v.Set(value)
 	}
+	return fmt.Errorf("field %s can't be set from a string", f.path)
+}
+
+// parses the passed in string as a new value of the passed in custom type using its encoding.TextUnmarshaler or
+// flag.Value implementation
+func parseText(typ reflect.Type, value string) (reflect.Value, error) {
+	ptr := reflect.New(typ)
 
-	// build our keys and sort them
-	keys := make([]string, 0)
-	for k := range fields {
-		keys = append(keys, k)
+	switch v := ptr.Interface().(type) {
+	case encoding.TextUnmarshaler:
+		return ptr.Elem(), v.UnmarshalText([]byte(value))
+	case flag.Value:
+		return ptr.Elem(), v.Set(value)
 	}
-	sort.Strings(keys)
+	return ptr.Elem(), fmt.Errorf("%s can't be parsed from a string", typ)
+}
+
+// returns the value of a custom type field as a string using its encoding.TextMarshaler or flag.Value implementation
+func (f *ezField) text() string {
+	return textValue(f.value)
+}
 
-	return &ezFields{keys, fields}, nil
+// returns the passed in value of a custom type as a string using its encoding.TextMarshaler or flag.Value implementation
+func textValue(value reflect.Value) string {
+	// methods may have pointer receivers so we need an addressable value, which might need to be a copy
+	ptr := reflect.New(value.Type())
+	ptr.Elem().Set(value)
+
+	switch v := ptr.Interface().(type) {
+	case encoding.TextMarshaler:
+		if b, err := v.MarshalText(); err == nil {
+			return string(b)
+		}
+	case flag.Value:
+		return v.String()
+	}
+	return fmt.Sprint(value.Interface())
 }
 
-// utility struct for holding the snaked key, raw key (env all caps or flag) along with a read value
-type ezValue struct {
-	rawKey string
-	value  string
+// returns the value of the passed in field as a string in the same format it is read from env vars and flags
+func formatValue(f *ezField) string {
+	switch v := f.Value().(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ",")
+	case []int:
+		parts := make([]string, len(v))
+		for i, n := range v {
+			parts[i] = strconv.Itoa(n)
+		}
+		return strings.Join(parts, ",")
+	case time.Time:
+		return formatDatetime(v)
+	}
+	return f.text()
 }
 
 // utility struct that holds our fields and an ordered list of the keys for predictable iteration
 type ezFields struct {
 	keys   []string
-	fields map[string]*structs.Field
+	fields map[string]*ezField
 }
 
-func printFields(header string, fields *ezFields) {
-	fmt.Printf("CONF: %s\n", header)
+// logs the current value of each of our fields, along with where it came from if origins are passed in
+func logFields(log *slog.Logger, msg string, fields *ezFields, origins map[string]Origin) {
 	for _, k := range fields.keys {
-		field := fields.fields[k]
-		fmt.Printf("CONF: % 40s = %v\n", field.Name(), field.Value())
+		attrs := []any{"field", k, "value", displayValue(fields.fields[k])}
+		if origin, found := origins[k]; found {
+			attrs = append(attrs, "source", origin.String())
+		}
+		log.Info(msg, attrs...)
 	}
-	fmt.Println()
 }
 
-func printValues(header string, values map[string]ezValue) {
-	fmt.Printf("CONF: %s\n", header)
-	for _, v := range values {
-		fmt.Printf("CONF: % 40s = %s\n", v.rawKey, v.value)
+// logs the values which were set from a single source
+func logValues(log *slog.Logger, msg string, fields *ezFields, values map[string]SourceValue) {
+	for _, k := range sortedKeys(values) {
+		log.Info(msg, "field", k, "value", displayValue(fields.fields[k]), "source", values[k].Origin.String())
 	}
-	fmt.Println()
 }
@@ -1,9 +1,15 @@
 package ezconf
 
 import (
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/netip"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -17,6 +23,7 @@ type allKinds struct {
 	MyBool     bool
 	MyString   string
 	MyDatetime time.Time
+	MyDuration time.Duration
 }
 
 type allTypes struct {
@@ -37,6 +44,7 @@ type allTypes struct {
 	MyStrings  []string
 	MyInts     []int
 	MyDatetime time.Time
+	MyDuration time.Duration
 	MyLogLevel slog.Level
 }
 
@@ -49,6 +57,14 @@ func toFields(t *testing.T, s any) *ezFields {
 	return fields
 }
 
+// options for loggers in tests which leave out the time so that output is predictable
+var testLogOptions = &slog.HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey {
+		return slog.Attr{}
+	}
+	return a
+}}
+
 func TestSetValue(t *testing.T) {
 	at := allTypes{}
 	fields := toFields(t, &at)
@@ -105,6 +121,10 @@ func TestSetValue(t *testing.T) {
 		{"my_datetime", "2018-04-03T05:30:00.123+07:00", false, "2018-04-03 05:30:00.123 +0700 +0700"},
 		{"my_datetime", "notdate", true, ""},
 
+		{"my_duration", "15s", false, "15s"},
+		{"my_duration", "1m30s", false, "1m30s"},
+		{"my_duration", "15", true, ""},
+
 		{"my_log_level", "info", false, "INFO"},
 		{"my_log_level", "ERROR", false, "ERROR"},
 		{"my_log_level", "crazy", true, ""},
@@ -113,7 +133,7 @@ func TestSetValue(t *testing.T) {
 	}
 
 	for _, tc := range tests {
-		values := map[string]ezValue{tc.key: {tc.key, tc.value}}
+		values := map[string]SourceValue{tc.key: {Value: tc.value}}
 		err := setValues(fields, values)
 		if !tc.hasErr && err != nil {
 			assert.NoError(t, err, "unexpected error setting %s to %s", tc.key, tc.value)
@@ -132,13 +152,79 @@ func TestSetValue(t *testing.T) {
 func TestEndToEnd(t *testing.T) {
 	at := &allTypes{}
 	conf := NewLoader(at, "foo", "description", []string{"testdata/missing.toml", "testdata/fields.toml", "testdata/simple.toml"})
-	conf.SetArgs("-my-int=48", "-my-log-level=error", "-debug-conf")
+	conf.SetArgs("-my-int=48", "-my-log-level=error", "-my-duration=1m30s", "-debug-conf")
 	err := conf.Load()
 	assert.NoError(t, err)
 	assert.Equal(t, 48, at.MyInt)
+	assert.Equal(t, 90*time.Second, at.MyDuration)
 	assert.Equal(t, slog.LevelError, at.MyLogLevel)
 }
 
+func TestHelpAndFlagErrors(t *testing.T) {
+	at := &allTypes{MyInt: 12}
+
+	// asking for help returns ErrHelp without loading anything
+	for _, arg := range []string{"-help", "-h", "--help"} {
+		conf := NewLoader(at, "foo", "description", []string{"testdata/simple.toml"})
+		conf.SetArgs(arg, "-my-int=48")
+		err := conf.Load()
+		assert.ErrorIs(t, err, ErrHelp)
+		assert.Equal(t, 12, at.MyInt)
+	}
+
+	// flag parse errors are returned as values
+	conf := NewLoader(at, "foo", "description", nil)
+	conf.SetArgs("-unknown=bar")
+	assert.EqualError(t, conf.Load(), "flag provided but not defined: -unknown")
+
+	conf.SetArgs("-my-int=abc")
+	assert.EqualError(t, conf.Load(), `invalid value "abc" for flag -my-int: parse error`)
+}
+
+func TestOutputAndLogger(t *testing.T) {
+	type config struct {
+		NumWorkers int
+		APIKey     string `secret:"true"`
+	}
+
+	output := &bytes.Buffer{}
+	logs := &bytes.Buffer{}
+
+	logger := slog.New(slog.NewJSONHandler(logs, testLogOptions))
+	newLoader := func(args ...string) *Loader {
+		conf := NewLoader(&config{NumWorkers: 2}, "foo", "description", []string{"testdata/missing.toml", "testdata/workers.toml"})
+		conf.SetOutput(output)
+		conf.SetLogger(logger)
+		conf.SetArgs(args...)
+		return conf
+	}
+
+	// without -debug-conf nothing is logged
+	assert.NoError(t, newLoader().Load())
+	assert.Equal(t, "", logs.String())
+
+	conf := newLoader("-debug-conf", "-api-key=sesame")
+	assert.NoError(t, conf.Load())
+	assert.Equal(t, `{"level":"INFO","msg":"default value","field":"api_key","value":""}
+{"level":"INFO","msg":"default value","field":"num_workers","value":"2"}
+{"level":"INFO","msg":"skipping missing file","file":"testdata/missing.toml"}
+{"level":"INFO","msg":"parsing file","file":"testdata/workers.toml"}
+{"level":"INFO","msg":"key read from file","field":"num_workers","source":"file testdata/workers.toml:1"}
+{"level":"INFO","msg":"value overridden","field":"num_workers","value":"4","source":"file testdata/workers.toml:1"}
+{"level":"INFO","msg":"value overridden","field":"api_key","value":"********","source":"flag -api-key"}
+{"level":"INFO","msg":"final value","field":"api_key","value":"********","source":"flag -api-key"}
+{"level":"INFO","msg":"final value","field":"num_workers","value":"4","source":"file testdata/workers.toml:1"}
+`, logs.String())
+	assert.Equal(t, "", output.String())
+
+	// usage is written to our output
+	conf = newLoader("-help")
+	assert.Equal(t, ErrHelp, conf.Load())
+	conf.Usage()
+	assert.Contains(t, output.String(), "description\n\nUsage of foo:\n")
+	assert.Contains(t, output.String(), "FOO_NUM_WORKERS - int")
+}
+
 func TestNameTagValidation(t *testing.T) {
 	tests := []struct {
 		name string
@@ -185,6 +271,29 @@ func TestNameTagValidation(t *testing.T) {
 	assert.Contains(t, fields.fields, "opensearch")
 }
 
+func TestReservedNames(t *testing.T) {
+	type genConfConfig struct {
+		GenConf bool
+	}
+	_, err := buildFields(&genConfConfig{})
+	assert.EqualError(t, err, "GenConf name gen_conf is reserved for the -gen-conf flag")
+
+	type debugConfig struct {
+		Debug struct {
+			Conf string
+		}
+	}
+	conf := NewLoader(&debugConfig{}, "foo", "description", nil)
+	conf.SetArgs()
+	assert.EqualError(t, conf.Load(), "Debug.Conf name debug_conf is reserved for the -debug-conf flag")
+
+	type helpConfig struct {
+		ShowHelp bool `name:"help"`
+	}
+	_, err = buildFields(&helpConfig{})
+	assert.EqualError(t, err, "ShowHelp name help is reserved for the -help flag")
+}
+
 func TestNameTag(t *testing.T) {
 	type config struct {
 		OpenSearch string `name:"opensearch" help:"the OpenSearch URL"`
@@ -259,3 +368,253 @@ func TestPriority(t *testing.T) {
 
 	assert.Equal(t, 56, at.MyInt)
 }
+
+func TestNestedFields(t *testing.T) {
+	type config struct {
+		NumWorkers int
+		DB         struct {
+			URL      string
+			PoolSize int `help:"the size of the pool"`
+		}
+		Storage struct {
+			S3 struct {
+				Bucket string
+			} `name:"s3"`
+		} `name:"store"`
+	}
+
+	c := &config{}
+	fields := toFields(t, c)
+	assert.Equal(t, []string{"db_pool_size", "db_url", "num_workers", "store_s3_bucket"}, fields.keys)
+	assert.Equal(t, "DB.PoolSize", fields.fields["db_pool_size"].path)
+	assert.Equal(t, "Storage.S3.Bucket", fields.fields["store_s3_bucket"].path)
+
+	// nested values can be set from env and flags
+	conf := NewLoader(c, "foo", "description", []string{"testdata/nested.toml"})
+	conf.SetArgs("-db-pool-size=16", "-store-s3-bucket=flag-bucket", "-debug-conf")
+	t.Setenv("FOO_DB_URL", "postgres://env")
+	t.Setenv("FOO_DB_POOL_SIZE", "8")
+
+	err := conf.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, 12, c.NumWorkers)
+	assert.Equal(t, "postgres://env", c.DB.URL)
+	assert.Equal(t, 16, c.DB.PoolSize)
+	assert.Equal(t, "flag-bucket", c.Storage.S3.Bucket)
+
+	// nested names can collide with top level names
+	type collides struct {
+		DBPoolSize int
+		DB         struct {
+			PoolSize int
+		}
+	}
+	_, err = buildFields(&collides{})
+	assert.EqualError(t, err, "DBPoolSize name collides with DB.PoolSize")
+}
+
+// custom enum type which implements encoding.TextMarshaler and encoding.TextUnmarshaler
+type logFormat int
+
+const (
+	formatText logFormat = iota
+	formatJSON
+)
+
+func (f logFormat) MarshalText() ([]byte, error) {
+	return []byte([]string{"text", "json"}[f]), nil
+}
+
+func (f *logFormat) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "text":
+		*f = formatText
+	case "json":
+		*f = formatJSON
+	default:
+		return errors.New("invalid log format")
+	}
+	return nil
+}
+
+// custom type which only implements flag.Value
+type hostList []string
+
+func (h *hostList) String() string     { return strings.Join(*h, ";") }
+func (h *hostList) Set(s string) error { *h = strings.Split(s, ";"); return nil }
+
+func TestCustomTypes(t *testing.T) {
+	type config struct {
+		Format logFormat `help:"the log format"`
+		IP     net.IP
+		Addr   netip.Addr
+		Hosts  hostList
+		Nested struct {
+			Hosts hostList
+		}
+	}
+
+	c := &config{Format: formatText, IP: net.IPv4(127, 0, 0, 1), Hosts: hostList{"localhost"}}
+	fields := toFields(t, c)
+	assert.Equal(t, []string{"addr", "format", "hosts", "ip", "nested_hosts"}, fields.keys)
+
+	// defaults are rendered using MarshalText or String
+	fs := buildFlags("foo", "description", fields, flag.ContinueOnError)
+	assert.Equal(t, "text", fs.Lookup("format").DefValue)
+	assert.Equal(t, "127.0.0.1", fs.Lookup("ip").DefValue)
+	assert.Equal(t, "localhost", fs.Lookup("hosts").DefValue)
+	assert.Contains(t, buildEnvUsage("foo", fields), "FOO_ADDR - netip.Addr")
+
+	conf := NewLoader(c, "foo", "description", []string{"testdata/custom.toml"})
+	conf.SetArgs("-ip=10.1.1.1", "-nested-hosts=d.com;e.com")
+	t.Setenv("FOO_HOSTS", "env.com")
+
+	err := conf.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, formatJSON, c.Format)
+	assert.Equal(t, "10.1.1.1", c.IP.String())
+	assert.Equal(t, netip.MustParseAddr("10.0.0.1"), c.Addr)
+	assert.Equal(t, hostList{"env.com"}, c.Hosts)
+	assert.Equal(t, hostList{"d.com", "e.com"}, c.Nested.Hosts)
+
+	// errors from parsing are returned
+	conf = NewLoader(c, "foo", "description", nil)
+	conf.SetArgs("-format=xml")
+	err = conf.Load()
+	assert.EqualError(t, err, "invalid value xml for format from flag -format: invalid log format")
+}
+
+func TestSources(t *testing.T) {
+	type config struct {
+		NumWorkers int
+		LogLevel   slog.Level
+		Labels     map[string]string
+		DB         struct {
+			URL      string
+			PoolSize int
+		}
+	}
+
+	c := &config{NumWorkers: 2}
+	conf := NewLoader(c, "foo", "description", []string{"testdata/base.toml", "testdata/local.toml"})
+	assert.Nil(t, conf.Sources())
+
+	conf.SetMergeFiles(true)
+	conf.SetArgs("-db-pool-size=32")
+	t.Setenv("FOO_NUM_WORKERS", "64")
+
+	err := conf.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldSource{
+		{"db_pool_size", 32, Origin{Kind: OriginFlag, Name: "-db-pool-size"}},
+		{"db_url", "postgres://base", Origin{Kind: OriginFile, Name: "testdata/base.toml", Line: 8}},
+		{"log_level", slog.LevelDebug, Origin{Kind: OriginFile, Name: "testdata/local.toml", Line: 1}},
+		{"num_workers", 64, Origin{Kind: OriginEnv, Name: "FOO_NUM_WORKERS"}},
+	}, conf.Sources())
+
+	// without any sources everything comes from defaults
+	c = &config{NumWorkers: 2}
+	conf = NewLoader(c, "foo", "description", nil)
+	conf.SetArgs()
+	t.Setenv("FOO_NUM_WORKERS", "")
+
+	err = conf.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "num_workers=2 (default)", conf.Sources()[3].String())
+}
+
+func TestRequired(t *testing.T) {
+	type config struct {
+		DB         string `required:"true"`
+		NumWorkers int    `required:"true"`
+		LogLevel   slog.Level
+		S3         struct {
+			Bucket string `required:"true"`
+		}
+	}
+
+	// all missing fields are reported together
+	c := &config{DB: "postgres://default"}
+	conf := NewLoader(c, "foo", "description", nil)
+	conf.SetArgs()
+	err := conf.Load()
+	assert.EqualError(t, err, "missing required value for db, set it in a config file, with FOO_DB or with -db\n"+
+		"missing required value for num_workers, set it in a config file, with FOO_NUM_WORKERS or with -num-workers\n"+
+		"missing required value for s3_bucket, set it in a config file, with FOO_S3_BUCKET or with -s3-bucket")
+
+	// any source can provide a required value
+	c = &config{}
+	conf = NewLoader(c, "foo", "description", []string{"testdata/workers.toml"})
+	conf.SetArgs("-s3-bucket=uploads")
+	t.Setenv("FOO_DB", "postgres://env")
+
+	err = conf.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://env", c.DB)
+	assert.Equal(t, 4, c.NumWorkers)
+	assert.Equal(t, "uploads", c.S3.Bucket)
+}
+
+func TestLoadTyped(t *testing.T) {
+	type config struct {
+		NumWorkers int
+		DB         string
+		Labels     []string
+	}
+	defaults := config{NumWorkers: 2, Labels: []string{"courier"}}
+
+	c, err := Load(defaults,
+		WithName("courier"),
+		WithDescription("description"),
+		WithFiles("testdata/missing.toml", "testdata/workers.toml"),
+		WithEnvFiles("testdata/base.env"),
+		WithEnv(map[string]string{"COURIER_DB": "postgres://remote/courier"}),
+		WithArgs("-labels=a,b"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, &config{NumWorkers: 8, DB: "postgres://remote/courier", Labels: []string{"a", "b"}}, c)
+	assert.Equal(t, config{NumWorkers: 2, Labels: []string{"courier"}}, defaults)
+
+	// the environment passed in replaces the process environment
+	t.Setenv("COURIER_NUM_WORKERS", "16")
+	c, err = Load(defaults, WithName("courier"), WithEnv(map[string]string{}), WithArgs())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.NumWorkers)
+
+	c, err = Load(defaults, WithName("courier"), WithEnv(map[string]string{"COURIER_NUM_WORKES": "4"}), WithUnknownEnv(UnknownEnvError), WithArgs())
+	assert.EqualError(t, err, "unknown environment variable COURIER_NUM_WORKES, did you mean COURIER_NUM_WORKERS?")
+	assert.Nil(t, c)
+
+	// configs must be structs
+	_, err = Load(&defaults, WithArgs())
+	assert.EqualError(t, err, "config must be a struct, not *ezconf.config")
+	_, err = Load(5, WithArgs())
+	assert.EqualError(t, err, "config must be a struct, not int")
+
+	_, err = buildFields(&c)
+	assert.EqualError(t, err, "config must be a struct or a pointer to a struct, not **ezconf.config")
+
+	// a name is required
+	_, err = Load(defaults, WithArgs())
+	assert.EqualError(t, err, "name must be set with WithName as it is used for environment variables and help")
+
+	// loaders need a pointer to load into
+	for _, config := range []any{defaults, (*config)(nil), &c} {
+		conf := NewLoader(config, "foo", "description", nil)
+		conf.SetArgs()
+		assert.EqualError(t, conf.Load(), fmt.Sprintf("config must be a non-nil pointer to a struct, not %T", config))
+	}
+
+	// errors are returned without a config
+	c, err = Load(defaults, WithName("courier"), WithArgs("-num-workers=x"))
+	assert.EqualError(t, err, `invalid value "x" for flag -num-workers: parse error`)
+	assert.Nil(t, c)
+
+	// usage is written before returning ErrHelp
+	output := &bytes.Buffer{}
+	c, err = Load(defaults, WithName("courier"), WithDescription("my description"), WithOutput(output), WithArgs("-help"))
+	assert.ErrorIs(t, err, ErrHelp)
+	assert.Nil(t, c)
+	assert.Contains(t, output.String(), "my description")
+	assert.Contains(t, output.String(), "COURIER_NUM_WORKERS")
+}
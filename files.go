@@ -0,0 +1,164 @@
+package ezconf
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/naoina/toml"
+	"github.com/naoina/toml/ast"
+)
+
+// fileSource is the Source for TOML, YAML and JSON files. As files can contain values for maps and arrays of tables
+// which can't be set from strings, files are decoded directly into our config, and the values returned are the
+// decoded values of our fields.
+type fileSource struct {
+	config any
+	files  []string
+	merge  bool
+	strict bool
+	log    *slog.Logger
+	fields *ezFields
+}
+
+func (s *fileSource) Values(keys []string) (map[string]SourceValue, error) {
+	textValues, origins, err := parseFiles(s.config, s.files, s.merge, s.strict, s.log)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]SourceValue, len(origins))
+	for k, origin := range origins {
+		if text, found := textValues[k]; found {
+			values[k] = SourceValue{text, origin}
+		} else if f, found := s.fields.fields[k]; found {
+			values[k] = SourceValue{f.Value(), origin}
+		}
+	}
+	return values, nil
+}
+
+// Iterates the list of TOML, YAML or JSON files, parsing the first that is found and loading the
+// result into the passed in struct pointer. If merge is true, all found files are
+// parsed in order with later files overriding keys from earlier ones. If no files
+// are passed in or no files are found, this is a noop.
+//
+// Returns any values for custom types which can only be set from strings to be set
+// on our fields, as well as where each key that maps to a field was read from.
+// If strict is true, files containing keys which don't map to our config are rejected. Which files are read is
+// logged to the passed in logger.
+func parseFiles(config any, files []string, merge bool, strict bool, log *slog.Logger) (map[string]string, map[string]Origin, error) {
+	values := make(map[string]string)
+	origins := make(map[string]Origin)
+	var merged *ast.Table
+
+	// search through our list of files, stopping when we find one unless we are merging
+	for i, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			// not finding a file is ok, we just move on
+			if os.IsNotExist(err) {
+				log.Info("skipping missing file", "file", file)
+				continue
+			}
+			return nil, nil, err
+		}
+		log.Info("parsing file", "file", file)
+
+		// if we can't parse this file, that's a nogo
+		table, err := parseFile(file, data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		if strict {
+			if err := checkUnknownKeys(file, table, config); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// decode into an empty config to check this file, as maps are only merged once all files are read
+		fileValues, fileLines, err := decodeTable(table, reflect.New(reflect.TypeOf(config).Elem()).Interface())
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing %s: %w", file, err)
+		}
+		merged = mergeTables(merged, table)
+
+		for k, line := range fileLines {
+			origins[k] = Origin{Kind: OriginFile, Name: file, Line: line}
+		}
+		for k, v := range fileValues {
+			values[k] = v
+		}
+
+		if merge {
+			continue
+		}
+
+		for i = i + 1; i < len(files); i++ {
+			log.Info("previous file found, skipping file", "file", files[i])
+		}
+
+		// we break at the first file we find
+		break
+	}
+
+	if merged != nil {
+		if err := newTOMLConfig().UnmarshalTable(merged, config); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, k := range sortedKeys(origins) {
+		log.Info("key read from file", "field", k, "source", origins[k].String())
+	}
+
+	return values, origins, nil
+}
+
+// merges the passed in TOML table into the base table, with its keys overriding the same keys in the base table,
+// including inside nested tables, and returns the result
+func mergeTables(base, table *ast.Table) *ast.Table {
+	if base == nil {
+		return table
+	}
+
+	for key, value := range table.Fields {
+		baseSub, baseIsTable := base.Fields[key].(*ast.Table)
+		sub, isTable := value.(*ast.Table)
+		if baseIsTable && isTable {
+			base.Fields[key] = mergeTables(baseSub, sub)
+		} else {
+			base.Fields[key] = value
+		}
+	}
+	return base
+}
+
+// parses the passed in file contents into a TOML table, using the extension of the file to decide its format.
+// Files which aren't TOML are converted so that all formats are decoded with the same rules.
+func parseFile(file string, data []byte) (*ast.Table, error) {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return parseYAML(data)
+	case ".json":
+		return parseJSON(data)
+	}
+	return toml.Parse(data)
+}
+
+// returns an error reporting every key in the passed in file which doesn't map to our config
+func checkUnknownKeys(file string, table *ast.Table, config any) error {
+	var errs []error
+	for _, u := range findUnknownKeys(table, reflect.TypeOf(config)) {
+		if u.suggestion != "" {
+			errs = append(errs, fmt.Errorf("%s:%d: unknown key %s, did you mean %s?", file, u.line, u.key, u.suggestion))
+		} else {
+			errs = append(errs, fmt.Errorf("%s:%d: unknown key %s", file, u.line, u.key))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,85 @@
+package ezconf
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeFiles(t *testing.T) {
+	type config struct {
+		NumWorkers int
+		LogLevel   slog.Level
+		Labels     map[string]string
+		DB         struct {
+			URL      string
+			PoolSize int
+		}
+	}
+
+	files := []string{"testdata/base.toml", "testdata/missing.toml", "testdata/local.toml"}
+
+	// without merging only the first found file is read
+	c := &config{}
+	_, keys, err := parseFiles(c, files, false, false, slog.New(slog.DiscardHandler))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, c.NumWorkers)
+	assert.Equal(t, 4, c.DB.PoolSize)
+	assert.Equal(t, "postgres://base", c.DB.URL)
+	assert.Equal(t, "testdata/base.toml", keys["db_pool_size"].Name)
+
+	// with merging later files override keys from earlier ones, and the keys of tables are merged
+	c = &config{}
+	_, keys, err = parseFiles(c, files, true, false, slog.New(slog.DiscardHandler))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, c.NumWorkers)
+	assert.Equal(t, slog.LevelDebug, c.LogLevel)
+	assert.Equal(t, 16, c.DB.PoolSize)
+	assert.Equal(t, "postgres://base", c.DB.URL)
+	assert.Equal(t, map[string]string{"env": "local", "team": "ops", "region": "eu"}, c.Labels)
+	assert.Equal(t, map[string]Origin{
+		"num_workers":  {Kind: OriginFile, Name: "testdata/base.toml", Line: 1},
+		"log_level":    {Kind: OriginFile, Name: "testdata/local.toml", Line: 1},
+		"db_url":       {Kind: OriginFile, Name: "testdata/base.toml", Line: 8},
+		"db_pool_size": {Kind: OriginFile, Name: "testdata/local.toml", Line: 4},
+	}, keys)
+
+	// errors include the file
+	_, _, err = parseFiles(c, []string{"testdata/base.toml", "testdata/simple.toml"}, true, false, slog.New(slog.DiscardHandler))
+	assert.ErrorContains(t, err, "error parsing testdata/simple.toml: ")
+}
+
+func TestStrictFiles(t *testing.T) {
+	type config struct {
+		NumWorkers int
+		LogLevel   slog.Level
+		Labels     map[string]string
+		DB         struct {
+			URL      string
+			PoolSize int
+		}
+	}
+
+	log := slog.New(slog.DiscardHandler)
+
+	// every unknown key is reported with its line and a suggestion if there's a close match
+	_, _, err := parseFiles(&config{}, []string{"testdata/typos.toml"}, false, true, log)
+	assert.EqualError(t, err, "testdata/typos.toml:1: unknown key num_worker, did you mean num_workers?\n"+
+		"testdata/typos.toml:6: unknown key db.pol_size, did you mean db.pool_size?\n"+
+		"testdata/typos.toml:11: unknown key unrelated")
+
+	_, _, err = parseFiles(&config{}, []string{"testdata/typos.yaml"}, false, true, log)
+	assert.EqualError(t, err, "testdata/typos.yaml:4: unknown key db.pool_sise, did you mean db.pool_size?\n"+
+		"testdata/typos.yaml:5: unknown key dbs, did you mean db?")
+
+	// files without unknown keys are fine
+	c := &config{}
+	_, _, err = parseFiles(c, []string{"testdata/base.toml"}, false, true, log)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, c.DB.PoolSize)
+
+	// without strict the decoder fails on the first unknown key
+	_, _, err = parseFiles(&config{}, []string{"testdata/typos.toml"}, false, false, log)
+	assert.Error(t, err)
+}
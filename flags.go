@@ -4,27 +4,36 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
-func parseFlags(fs *flag.FlagSet, args []string) (map[string]ezValue, error) {
-	values := make(map[string]ezValue)
+// the snake_case names of our built-in flags, which can't be used by fields
+var builtinFlags = []string{"help", "debug_conf", "gen_conf"}
 
-	err := fs.Parse(args)
-	if err != nil {
-		return nil, err
-	}
+// flagSource is the Source for command line parameters, which must have already been parsed
+type flagSource struct {
+	flags *flag.FlagSet
+}
+
+func (s *flagSource) Values(keys []string) (map[string]SourceValue, error) {
+	return flagValues(s.flags), nil
+}
+
+// returns the values of all the flags that were set when our flags were parsed
+func flagValues(fs *flag.FlagSet) map[string]SourceValue {
+	values := make(map[string]SourceValue)
 
 	// visit all our flags, populate a value for every value that isn't the default
 	fs.Visit(func(flag *flag.Flag) {
 		snake := strings.ReplaceAll(flag.Name, "-", "_")
-		if snake != "help" && snake != "debug_conf" {
-			values[snake] = ezValue{flag.Name, flag.Value.String()}
+		if !slices.Contains(builtinFlags, snake) {
+			values[snake] = SourceValue{flag.Value.String(), Origin{Kind: OriginFlag, Name: "-" + flag.Name}}
 		}
 	})
-	return values, nil
+	return values
 }
 
 func buildFlags(name string, description string, fields *ezFields, errorHandling flag.ErrorHandling) *flag.FlagSet {
@@ -42,20 +51,24 @@ func buildFlags(name string, description string, fields *ezFields, errorHandling
 		fmt.Fprint(flags.Output(), buildEnvUsage(name, fields))
 	}
 
-	// add our default help and debug-conf flags
+	// add our default help, debug-conf and gen-conf flags
 	flags.Bool("help", false, "print usage information")
 	flags.Bool("debug-conf", false, "print where config values are coming from")
+	flags.Bool("gen-conf", false, "print a sample TOML config file with default values")
 
 	// build a flag for each supported field
 	for _, name := range fields.keys {
 		f := fields.fields[name]
 
-		// change underscores to dashes for flags
-		flagName := strings.ReplaceAll(name, "_", "-")
+		flagName := toFlagName(name)
 		help := f.Tag("help")
 		if help == "" {
 			help = fmt.Sprintf("set value for %s", name)
 		}
+		help += f.rules.describe()
+		if f.restartOnly {
+			help += " (requires restart)"
+		}
 
 		switch v := f.Value().(type) {
 		case int:
@@ -104,10 +117,40 @@ func buildFlags(name string, description string, fields *ezFields, errorHandling
 		case time.Time:
 			flags.String(flagName, formatDatetime(f.Value().(time.Time)), help)
 
+		case time.Duration:
+			flags.Duration(flagName, v, help)
+
 		case slog.Level:
 			flags.String(flagName, v.String(), help)
+
+		default:
+			// custom types are parsed from their string value once all our sources are read
+			flags.String(flagName, f.text(), help)
+		}
+
+		// don't show secrets as defaults in our usage
+		if display, wasRedacted := redactValue(f, formatValue(f)); wasRedacted {
+			flags.Lookup(flagName).DefValue = display
 		}
 	}
 
 	return flags
 }
+
+// returns the passed in error from parsing our flags with the value redacted if it was for a secret field, as the
+// errors of the flag package include the value, e.g. invalid value "abc" for flag -pin: parse error
+func redactFlagError(err error, fields *ezFields) error {
+	for _, name := range fields.keys {
+		flagName := toFlagName(name)
+		msg := err.Error()
+		if isSecret(fields.fields[name]) && (strings.Contains(msg, " for flag -"+flagName+": ") || strings.Contains(msg, " for -"+flagName+": ")) {
+			return fmt.Errorf("invalid value %s for flag -%s", redacted, flagName)
+		}
+	}
+	return err
+}
+
+// returns the flag name for the passed in snake_case field name, we change underscores to dashes for flags
+func toFlagName(snake string) string {
+	return strings.ReplaceAll(snake, "_", "-")
+}
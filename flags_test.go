@@ -15,6 +15,7 @@ func TestBuildFlags(t *testing.T) {
 		MyBool:     true,
 		MyString:   "foobar",
 		MyDatetime: time.Date(2018, 3, 5, 12, 30, 0, 0, time.UTC),
+		MyDuration: 90 * time.Second,
 	}
 	fs := buildFlags("foo", "description", toFields(t, as), flag.ContinueOnError)
 
@@ -28,6 +29,7 @@ func TestBuildFlags(t *testing.T) {
 		{"my-bool", "set value for my_bool", "true"},
 		{"my-string", "set value for my_string", "foobar"},
 		{"my-datetime", "set value for my_datetime", "2018-03-05T12:30:00Z"},
+		{"my-duration", "set value for my_duration", "1m30s"},
 	}
 
 	for _, ef := range flags {
@@ -48,7 +50,7 @@ func TestBuildFlags(t *testing.T) {
 	fs.Usage()
 
 	// parse with invalid args
-	_, err := parseFlags(fs, []string{"-unknown=bar"})
+	err := fs.Parse([]string{"-unknown=bar"})
 	if err == nil {
 		t.Errorf("should have errored with invalid args")
 	}
@@ -59,22 +61,26 @@ func TestBuildFlags(t *testing.T) {
 		"-my-int32=65",
 		"-my-bool=false",
 		"-my-datetime=2018-04-05T12:30:00Z",
+		"-my-duration=15s",
 	}
-	values, err := parseFlags(fs, args)
+	err = fs.Parse(args)
 	if err != nil {
 		t.Errorf("received error parsing flags")
 		return
 	}
+	values, err := (&flagSource{fs}).Values(nil)
+	assert.NoError(t, err)
 
 	tcs := []struct {
 		key    string
-		rawKey string
+		origin string
 		value  string
 	}{
-		{"my_int32", "my-int32", "65"},
-		{"my_bool", "my-bool", "false"},
-		{"my_string", "my-string", "foozap"},
-		{"my_datetime", "my-datetime", "2018-04-05T12:30:00Z"},
+		{"my_int32", "-my-int32", "65"},
+		{"my_bool", "-my-bool", "false"},
+		{"my_string", "-my-string", "foozap"},
+		{"my_datetime", "-my-datetime", "2018-04-05T12:30:00Z"},
+		{"my_duration", "-my-duration", "15s"},
 	}
 
 	for _, tc := range tcs {
@@ -84,7 +90,7 @@ func TestBuildFlags(t *testing.T) {
 			continue
 		}
 
-		assert.Equal(t, tc.rawKey, v.rawKey, "raw key mismatch for key %s", tc.key)
-		assert.Equal(t, tc.value, v.value, "value mismatch for key %s", tc.key)
+		assert.Equal(t, Origin{Kind: OriginFlag, Name: tc.origin}, v.Origin, "origin mismatch for key %s", tc.key)
+		assert.Equal(t, tc.value, v.Value, "value mismatch for key %s", tc.key)
 	}
 }
@@ -0,0 +1,64 @@
+package ezconf
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
+
+// Holder holds an immutable snapshot of a config of type T, which can be read from any number of goroutines while
+// it is being replaced, e.g. by a reload. Snapshots returned by Get must never be modified.
+type Holder[T any] struct {
+	current atomic.Pointer[T]
+}
+
+// NewHolder creates a new holder with the passed in config as its snapshot
+func NewHolder[T any](config *T) *Holder[T] {
+	h := &Holder[T]{}
+	h.current.Store(config)
+	return h
+}
+
+// Hold creates a new holder for the config of the passed in loader, which must be a *T. The holder is given a copy
+// of the config as its snapshot and is updated with a new snapshot every time the loader loads or reloads it.
+func Hold[T any](l *Loader) (*Holder[T], error) {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+
+	config, isT := l.Config().(*T)
+	if !isT {
+		return nil, fmt.Errorf("config is %T, not %T", l.Config(), config)
+	}
+
+	h := NewHolder(copyConfig(config))
+	l.holders = append(l.holders, func(config any) { h.current.Store(config.(*T)) })
+	return h, nil
+}
+
+// Get returns the current snapshot
+func (h *Holder[T]) Get() *T {
+	return h.current.Load()
+}
+
+// Update replaces the current snapshot with a copy of it which has been modified by the passed in function, which
+// may be called more than once if the snapshot is replaced concurrently. The new snapshot is returned. Note that
+// updates are replaced by the next load or reload of a holder created by Hold.
+func (h *Holder[T]) Update(fn func(config *T)) *T {
+	for {
+		current := h.current.Load()
+		updated := copyConfig(current)
+		fn(updated)
+
+		if h.current.CompareAndSwap(current, updated) {
+			return updated
+		}
+	}
+}
+
+// returns a deep copy of the passed in config, or a new zero config if it is nil
+func copyConfig[T any](config *T) *T {
+	if config == nil {
+		return new(T)
+	}
+	return deepCopy(reflect.ValueOf(config)).Interface().(*T)
+}
@@ -0,0 +1,96 @@
+package ezconf
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHolder(t *testing.T) {
+	type config struct {
+		NumWorkers int
+		Labels     map[string]string
+	}
+
+	h := NewHolder(&config{NumWorkers: 2, Labels: map[string]string{"app": "courier"}})
+	first := h.Get()
+	assert.Equal(t, 2, first.NumWorkers)
+
+	// updates are made to a copy so existing snapshots aren't modified
+	updated := h.Update(func(c *config) {
+		c.NumWorkers = 4
+		c.Labels["env"] = "prod"
+	})
+	assert.Equal(t, updated, h.Get())
+	assert.Equal(t, &config{NumWorkers: 4, Labels: map[string]string{"app": "courier", "env": "prod"}}, h.Get())
+	assert.Equal(t, &config{NumWorkers: 2, Labels: map[string]string{"app": "courier"}}, first)
+
+	// concurrent updates are all applied
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.Update(func(c *config) { c.NumWorkers++ })
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 24, h.Get().NumWorkers)
+
+	// a holder without a config updates a zero config
+	empty := &Holder[config]{}
+	assert.Nil(t, empty.Get())
+	assert.Equal(t, &config{NumWorkers: 1}, empty.Update(func(c *config) { c.NumWorkers = 1 }))
+}
+
+func TestHold(t *testing.T) {
+	type config struct {
+		NumWorkers int
+		DBURL      string `name:"db_url" reload:"false"`
+	}
+
+	file := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(file, []byte("num_workers = 4\n"), 0600)
+
+	c := &config{NumWorkers: 2, DBURL: "postgres://localhost"}
+	conf := NewLoader(c, "foo", "description", []string{file})
+	conf.SetArgs()
+
+	// config must be of the holder's type
+	_, err := Hold[struct{ Foo int }](conf)
+	assert.EqualError(t, err, "config is *ezconf.config, not *struct { Foo int }")
+
+	// before loading the holder has a copy of the defaults
+	h, err := Hold[config](conf)
+	assert.NoError(t, err)
+	assert.Equal(t, &config{NumWorkers: 2, DBURL: "postgres://localhost"}, h.Get())
+
+	// loading gives it a copy of the loaded config which can't be modified through our pointer
+	assert.NoError(t, conf.Load())
+	loaded := h.Get()
+	assert.Equal(t, &config{NumWorkers: 4, DBURL: "postgres://localhost"}, loaded)
+	assert.NotSame(t, c, loaded)
+	c.NumWorkers = 3
+	assert.Equal(t, 4, h.Get().NumWorkers)
+
+	// reloading gives it the new config
+	os.WriteFile(file, []byte("num_workers = 8\ndb_url = \"postgres://remote\"\n"), 0600)
+	assert.EqualError(t, conf.Reload(), "restart required to change db_url")
+	assert.Equal(t, &config{NumWorkers: 8, DBURL: "postgres://localhost"}, h.Get())
+	assert.Same(t, conf.Config(), h.Get())
+	assert.Equal(t, 4, loaded.NumWorkers)
+
+	// failed reloads don't change it
+	os.WriteFile(file, []byte("num_workers = \"x\"\n"), 0600)
+	assert.Error(t, conf.Reload())
+	assert.Equal(t, 8, h.Get().NumWorkers)
+
+	// holders created after loading start with a copy of the current config
+	h2, err := Hold[config](conf)
+	assert.NoError(t, err)
+	assert.Equal(t, h.Get(), h2.Get())
+	assert.NotSame(t, h.Get(), h2.Get())
+}
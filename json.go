@@ -0,0 +1,145 @@
+package ezconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/naoina/toml/ast"
+)
+
+// parses the passed in JSON document into a TOML table, objects becoming tables and arrays of objects becoming
+// arrays of tables, so that it can be decoded the same way as a TOML file
+func parseJSON(data []byte) (*ast.Table, error) {
+	p := &jsonParser{data: data, dec: json.NewDecoder(bytes.NewReader(data))}
+	p.dec.UseNumber()
+
+	tok, err := p.dec.Token()
+	if err == io.EOF {
+		return &ast.Table{Fields: make(map[string]any)}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("line %d: JSON document must be an object", p.line())
+	}
+
+	table, err := p.table(ast.TableTypeNormal)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("line %d: unexpected content after JSON object", p.line())
+	}
+	return table, nil
+}
+
+type jsonParser struct {
+	data []byte
+	dec  *json.Decoder
+}
+
+// returns the line number of the decoder's current position
+func (p *jsonParser) line() int {
+	return bytes.Count(p.data[:p.dec.InputOffset()], []byte("\n")) + 1
+}
+
+// reads the fields of an object whose opening brace has already been read
+func (p *jsonParser) table(typ ast.TableType) (*ast.Table, error) {
+	table := &ast.Table{Line: p.line(), Type: typ, Fields: make(map[string]any)}
+
+	for p.dec.More() {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+		line := p.line()
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := value.(type) {
+		case nil:
+			// null values are treated as not being set
+		case *ast.Table, []*ast.Table:
+			table.Fields[key] = v
+		case ast.Value:
+			table.Fields[key] = &ast.KeyValue{Key: key, Value: v, Line: line}
+		}
+	}
+
+	// read our closing brace
+	_, err := p.dec.Token()
+	return table, err
+}
+
+// reads the next value, returning a table for objects, a slice of tables for arrays of objects, nil for nulls and
+// a TOML value for everything else
+func (p *jsonParser) value() (any, error) {
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			return p.table(ast.TableTypeNormal)
+		}
+		return p.array()
+	case string:
+		return &ast.String{Value: v, Data: []rune(v)}, nil
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return &ast.Float{Value: v.String(), Data: []rune(v.String())}, nil
+		}
+		return &ast.Integer{Value: v.String(), Data: []rune(v.String())}, nil
+	case bool:
+		s := fmt.Sprint(v)
+		return &ast.Boolean{Value: s, Data: []rune(s)}, nil
+	}
+	return nil, nil
+}
+
+// reads the items of an array whose opening bracket has already been read
+func (p *jsonParser) array() (any, error) {
+	line := p.line()
+	var tables []*ast.Table
+	var values []ast.Value
+
+	for p.dec.More() {
+		item, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := item.(type) {
+		case *ast.Table:
+			v.Type = ast.TableTypeArray
+			tables = append(tables, v)
+		case ast.Value:
+			values = append(values, v)
+		default:
+			return nil, fmt.Errorf("line %d: unsupported JSON array item", line)
+		}
+	}
+
+	// read our closing bracket
+	if _, err := p.dec.Token(); err != nil {
+		return nil, err
+	}
+
+	if tables != nil {
+		if values != nil {
+			return nil, fmt.Errorf("line %d: JSON arrays can't mix objects and other values", line)
+		}
+		return tables, nil
+	}
+	return &ast.Array{Value: values}, nil
+}
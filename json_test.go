@@ -0,0 +1,67 @@
+package ezconf
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJSON(t *testing.T) {
+	type config struct {
+		MyInt      int
+		MyBool     bool
+		MyDatetime time.Time
+		MyDuration time.Duration
+		MyLogLevel slog.Level
+		MyInts     []int
+		MyStrings  []string
+		MyFloat    float64
+		OpenSearch string `name:"opensearch"`
+		Skipped    string
+		Nested     struct {
+			NestedInt      int
+			NestedDuration time.Duration
+		}
+		Labels  map[string]string
+		Servers []struct {
+			Host string
+			Port int
+		}
+	}
+
+	c := &config{Skipped: "default"}
+	_, keys, err := parseFiles(c, []string{"testdata/missing.json", "testdata/simple.json"}, false, false, slog.New(slog.DiscardHandler))
+	assert.NoError(t, err)
+	assert.Equal(t, 32, c.MyInt)
+	assert.True(t, c.MyBool)
+	assert.Equal(t, time.Date(2018, 4, 3, 5, 30, 0, 0, time.UTC), c.MyDatetime)
+	assert.Equal(t, 15*time.Second, c.MyDuration)
+	assert.Equal(t, slog.LevelInfo, c.MyLogLevel)
+	assert.Equal(t, []int{10, 20, 30}, c.MyInts)
+	assert.Equal(t, []string{"foo", "bar"}, c.MyStrings)
+	assert.Equal(t, 1.5, c.MyFloat)
+	assert.Equal(t, "http://from-json", c.OpenSearch)
+	assert.Equal(t, "default", c.Skipped)
+	assert.Equal(t, 64, c.Nested.NestedInt)
+	assert.Equal(t, 90*time.Second, c.Nested.NestedDuration)
+	assert.Equal(t, map[string]string{"env": "prod"}, c.Labels)
+	assert.Len(t, c.Servers, 2)
+	assert.Equal(t, "b.com", c.Servers[1].Host)
+	assert.Equal(t, 443, c.Servers[1].Port)
+	assert.Equal(t, Origin{Kind: OriginFile, Name: "testdata/simple.json", Line: 2}, keys["my_int"])
+	assert.Equal(t, Origin{Kind: OriginFile, Name: "testdata/simple.json", Line: 13}, keys["nested_nested_int"])
+
+	// empty documents are fine, but documents must be single objects
+	_, err = parseJSON([]byte(""))
+	assert.NoError(t, err)
+	_, err = parseJSON([]byte(`["foo"]`))
+	assert.EqualError(t, err, "line 1: JSON document must be an object")
+	_, err = parseJSON([]byte(`{"foo": 1} {}`))
+	assert.EqualError(t, err, "line 1: unexpected content after JSON object")
+	_, err = parseJSON([]byte(`{"foo": [{"bar": 1}, 2]}`))
+	assert.EqualError(t, err, "line 1: JSON arrays can't mix objects and other values")
+	_, err = parseJSON([]byte(`{"foo": `))
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package ezconf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// confHandler is the handler of our default logger, which writes records as lines prefixed with CONF:. Records for
+// fields are grouped under their message, with names and values aligned, e.g.
+//
+//	CONF: default value
+//	CONF:                              num_workers = 4
+type confHandler struct {
+	out   *confOutput
+	attrs []slog.Attr
+}
+
+// utility struct for the writer of a handler, shared with any handlers derived from it
+type confOutput struct {
+	mu     sync.Mutex
+	w      io.Writer
+	header string // the message of the group of field records we're writing, if any
+}
+
+func newConfHandler(w io.Writer) *confHandler {
+	return &confHandler{out: &confOutput{w: w}}
+}
+
+func (h *confHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+func (h *confHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := make(map[string]string)
+	var others []string
+	add := func(a slog.Attr) bool {
+		if a.Key == "field" || a.Key == "value" || a.Key == "source" {
+			attrs[a.Key] = a.Value.String()
+		} else {
+			others = append(others, fmt.Sprintf("%s=%s", a.Key, a.Value))
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	r.Attrs(add)
+
+	msg := r.Message
+	if r.Level >= slog.LevelWarn {
+		msg = r.Level.String() + " " + msg
+	}
+
+	h.out.mu.Lock()
+	defer h.out.mu.Unlock()
+
+	var b strings.Builder
+	if field, isField := attrs["field"]; isField {
+		if h.out.header != msg {
+			if h.out.header != "" {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "CONF: %s\n", msg)
+			h.out.header = msg
+		}
+		fmt.Fprintf(&b, "CONF: % 40s", field)
+		if value, found := attrs["value"]; found {
+			fmt.Fprintf(&b, " = %s", value)
+		}
+		if source, found := attrs["source"]; found {
+			fmt.Fprintf(&b, " (%s)", source)
+		}
+		b.WriteString("\n")
+	} else {
+		if h.out.header != "" {
+			b.WriteString("\n")
+			h.out.header = ""
+		}
+		fmt.Fprintf(&b, "CONF: %s", strings.Join(append([]string{msg}, others...), " "))
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(h.out.w, b.String())
+	return err
+}
+
+func (h *confHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &confHandler{out: h.out, attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)}
+}
+
+func (h *confHandler) WithGroup(name string) slog.Handler {
+	return h
+}
@@ -0,0 +1,36 @@
+package ezconf
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := slog.New(newConfHandler(buf))
+
+	log.Info("default value", "field", "num_workers", "value", 4)
+	log.Info("default value", "field", "db_url", "value", "postgres://localhost")
+	log.Info("parsing file", "file", "courier.toml")
+	log.Info("key read from file", "field", "num_workers", "source", "file courier.toml:1")
+	log.Info("final value", "field", "num_workers", "value", 8, "source", "file courier.toml:1")
+	log.With("trigger", "SIGHUP").Warn("restart required to apply config changes", "fields", []string{"db"})
+	log.Debug("not shown")
+
+	assert.Equal(t, `CONF: default value
+CONF:                              num_workers = 4
+CONF:                                   db_url = postgres://localhost
+
+CONF: parsing file file=courier.toml
+CONF: key read from file
+CONF:                              num_workers (file courier.toml:1)
+
+CONF: final value
+CONF:                              num_workers = 8 (file courier.toml:1)
+
+CONF: WARN restart required to apply config changes trigger=SIGHUP fields=[db]
+`, buf.String())
+}
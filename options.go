@@ -0,0 +1,69 @@
+package ezconf
+
+import (
+	"io"
+	"log/slog"
+)
+
+// Option configures a loader created by Load
+type Option func(*Loader)
+
+// WithName sets the name used to build environment variables and help, e.g. courier
+func WithName(name string) Option {
+	return func(l *Loader) { l.name = name }
+}
+
+// WithDescription sets the description shown in help
+func WithDescription(description string) Option {
+	return func(l *Loader) { l.description = description }
+}
+
+// WithFiles sets the optional files to read configuration from in priority order, as described in NewLoader
+func WithFiles(files ...string) Option {
+	return func(l *Loader) { l.files = files }
+}
+
+// WithMergeFiles controls whether all found files are read, as described in SetMergeFiles
+func WithMergeFiles(merge bool) Option {
+	return func(l *Loader) { l.SetMergeFiles(merge) }
+}
+
+// WithStrict controls whether files are checked for unknown keys, as described in SetStrict
+func WithStrict(strict bool) Option {
+	return func(l *Loader) { l.SetStrict(strict) }
+}
+
+// WithArgs overrides the command line arguments to be parsed, as described in SetArgs
+func WithArgs(args ...string) Option {
+	return func(l *Loader) { l.SetArgs(args...) }
+}
+
+// WithEnv overrides the environment variables to be read, as described in SetEnv
+func WithEnv(env map[string]string) Option {
+	return func(l *Loader) { l.SetEnv(env) }
+}
+
+// WithEnvFiles sets optional dotenv files to read environment variables from, as described in SetEnvFiles
+func WithEnvFiles(files ...string) Option {
+	return func(l *Loader) { l.SetEnvFiles(files...) }
+}
+
+// WithUnknownEnv controls what happens with unknown environment variables, as described in SetUnknownEnv
+func WithUnknownEnv(unknown UnknownEnv) Option {
+	return func(l *Loader) { l.SetUnknownEnv(unknown) }
+}
+
+// WithSource adds a custom source of values with the passed in priority, as described in AddSource
+func WithSource(source Source, priority int) Option {
+	return func(l *Loader) { l.AddSource(source, priority) }
+}
+
+// WithOutput sets where usage information is written, as described in SetOutput
+func WithOutput(w io.Writer) Option {
+	return func(l *Loader) { l.SetOutput(w) }
+}
+
+// WithLogger sets the logger used for the output of -debug-conf, as described in SetLogger
+func WithLogger(logger *slog.Logger) Option {
+	return func(l *Loader) { l.SetLogger(logger) }
+}
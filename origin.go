@@ -0,0 +1,48 @@
+package ezconf
+
+import (
+	"fmt"
+)
+
+// OriginKind is the kind of source that the value of a field was read from
+type OriginKind string
+
+const (
+	OriginDefault OriginKind = "default"
+	OriginFile    OriginKind = "file"
+	OriginEnv     OriginKind = "env"
+	OriginEnvFile OriginKind = "env file"
+	OriginFlag    OriginKind = "flag"
+)
+
+// Origin describes where the value of a field was read from
+type Origin struct {
+	Kind OriginKind
+	Name string // the file path, environment variable name or flag name
+	Line int    // the line number if read from a file
+}
+
+// String returns a description of the origin, e.g. "env COURIER_NUM_WORKERS" or "file courier.toml:12"
+func (o Origin) String() string {
+	switch o.Kind {
+	case OriginDefault:
+		return string(o.Kind)
+	case OriginFile, OriginEnvFile:
+		if o.Line > 0 {
+			return fmt.Sprintf("%s %s:%d", o.Kind, o.Name, o.Line)
+		}
+	}
+	return fmt.Sprintf("%s %s", o.Kind, o.Name)
+}
+
+// FieldSource describes where the final value of a field came from
+type FieldSource struct {
+	Field  string // the snake_case name of the field, e.g. num_workers
+	Value  any
+	Origin Origin
+}
+
+// String returns a description of the field's value and where it came from, e.g. "num_workers=64 (env COURIER_NUM_WORKERS)"
+func (s FieldSource) String() string {
+	return fmt.Sprintf("%s=%v (%s)", s.Field, s.Value, s.Origin)
+}
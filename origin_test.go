@@ -0,0 +1,28 @@
+package ezconf_test
+
+import (
+	"testing"
+
+	"github.com/nyaruka/ezconf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrigin(t *testing.T) {
+	tests := []struct {
+		origin   ezconf.Origin
+		expected string
+	}{
+		{ezconf.Origin{Kind: ezconf.OriginDefault}, "default"},
+		{ezconf.Origin{Kind: ezconf.OriginFile, Name: "courier.toml", Line: 12}, "file courier.toml:12"},
+		{ezconf.Origin{Kind: ezconf.OriginFile, Name: "courier.toml"}, "file courier.toml"},
+		{ezconf.Origin{Kind: ezconf.OriginEnv, Name: "COURIER_NUM_WORKERS"}, "env COURIER_NUM_WORKERS"},
+		{ezconf.Origin{Kind: ezconf.OriginFlag, Name: "-num-workers"}, "flag -num-workers"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, tc.origin.String())
+	}
+
+	s := ezconf.FieldSource{Field: "num_workers", Value: 64, Origin: ezconf.Origin{Kind: ezconf.OriginEnv, Name: "COURIER_NUM_WORKERS"}}
+	assert.Equal(t, "num_workers=64 (env COURIER_NUM_WORKERS)", s.String())
+}
@@ -0,0 +1,284 @@
+package ezconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ChangeFunc is called after a reload with the old and new configs, which are pointers to structs of the same type
+// as the one passed to NewLoader, and the snake_case names of the fields which changed, e.g. db_pool_size
+type ChangeFunc func(old, updated any, changed []string)
+
+// OnChange registers a function to be called whenever a reload changes any values. Functions are called once the
+// reload has completed, so they can use the loader, e.g. to create a Holder.
+func (l *Loader) OnChange(fn ChangeFunc) {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+
+	l.onChange = append(l.onChange, fn)
+}
+
+// Config returns the current config, which is the config passed to NewLoader until a reload replaces it with a new
+// struct of the same type. Reloads never modify a config that has been returned, so it is safe to read concurrently.
+func (l *Loader) Config() any {
+	if state := l.state.Load(); state != nil {
+		return state.config
+	}
+	return l.config
+}
+
+// Reload re-reads all of our sources into a fresh copy of the defaults of our config. If that loads without any
+// errors it becomes the current config returned by Config, and any functions registered with OnChange are called
+// if any values changed. If there are errors they are returned and the current config is kept. Changes to fields
+// tagged with `reload:"false"` aren't applied, and are reported by returning a RestartRequiredError.
+func (l *Loader) Reload() error {
+	_, err := l.reload()
+	return err
+}
+
+// reloads our config, returning the names of the fields which changed. Functions registered with OnChange are called
+// after our lock is released so that they can use the loader themselves, e.g. to reload again.
+func (l *Loader) reload() ([]string, error) {
+	changed, notify, err := l.reloadLocked()
+	if notify != nil {
+		notify()
+	}
+	return changed, err
+}
+
+// reloads our config while holding our lock, returning the names of the fields which changed and a function to
+// notify our OnChange functions if any did
+func (l *Loader) reloadLocked() ([]string, func(), error) {
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+
+	current := l.state.Load()
+	if current == nil {
+		return nil, nil, errors.New("config must be loaded before it can be reloaded")
+	}
+
+	config := deepCopy(reflect.ValueOf(l.defaults)).Interface()
+	state, err := l.load(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// fields which can't be reloaded keep their current values and origins
+	oldValue, newValue := reflect.Indirect(reflect.ValueOf(current.config)), reflect.Indirect(reflect.ValueOf(config))
+	restartRequired := keepRestartOnlyFields(oldValue, newValue, "", false)
+	for _, name := range restartRequired {
+		if origin, found := current.origins[name]; found {
+			state.origins[name] = origin
+		}
+	}
+
+	l.state.Store(state)
+	for _, hold := range l.holders {
+		hold(config)
+	}
+
+	var notify func()
+	changed := changedFields(oldValue, newValue, "")
+	if len(changed) > 0 {
+		onChange := slices.Clone(l.onChange)
+		notify = func() {
+			for _, fn := range onChange {
+				fn(current.config, config, changed)
+			}
+		}
+	}
+
+	if len(restartRequired) > 0 {
+		return changed, notify, &RestartRequiredError{Fields: restartRequired}
+	}
+	return changed, notify, nil
+}
+
+// RestartRequiredError is returned by Reload when fields tagged with `reload:"false"`, or fields of structs tagged
+// with it, have changed. Those fields keep their current values until a restart, but all other changes are applied.
+type RestartRequiredError struct {
+	Fields []string // the snake_case names of the fields which changed
+}
+
+func (e *RestartRequiredError) Error() string {
+	return fmt.Sprintf("restart required to change %s", strings.Join(e.Fields, ", "))
+}
+
+// resets any fields in the updated struct which can't be reloaded to their values in the old struct, returning the
+// sorted snake_case names of those which had changed
+func keepRestartOnlyFields(old, updated reflect.Value, prefix string, restartOnly bool) []string {
+	var kept []string
+
+	for i := 0; i < old.NumField(); i++ {
+		sf := old.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := prefix + fieldKey(sf)
+		fieldRestartOnly := restartOnly || sf.Tag.Get("reload") == "false"
+		if sf.Type.Kind() == reflect.Struct && !isSupportedType(sf.Type) {
+			kept = append(kept, keepRestartOnlyFields(old.Field(i), updated.Field(i), name+"_", fieldRestartOnly)...)
+		} else if fieldRestartOnly && !reflect.DeepEqual(old.Field(i).Interface(), updated.Field(i).Interface()) {
+			updated.Field(i).Set(deepCopy(old.Field(i)))
+			kept = append(kept, name)
+		}
+	}
+
+	slices.Sort(kept)
+	return kept
+}
+
+// reloads our config, logging which fields changed or why the current config was kept
+func (l *Loader) reloadAndLog(trigger string) {
+	changed, err := l.reload()
+
+	var restartErr *RestartRequiredError
+	if errors.As(err, &restartErr) {
+		l.logger.Warn("restart required to apply config changes", "trigger", trigger, "fields", restartErr.Fields)
+	} else if err != nil {
+		l.logger.Error("error reloading config, keeping current config", "trigger", trigger, "error", err)
+		return
+	}
+	l.logger.Info("config reloaded", "trigger", trigger, "changed", changed)
+}
+
+// ReloadOnSIGHUP installs a handler for SIGHUP, e.g. from kill -HUP, which reloads our config until the passed in
+// context is cancelled. It returns once the handler is installed. Which fields changed, or any errors reloading, are
+// logged and the current config is kept if there are errors.
+func (l *Loader) ReloadOnSIGHUP(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(signals)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				l.reloadAndLog("SIGHUP")
+			}
+		}
+	}()
+}
+
+// Watch checks our config and dotenv files for changes every interval, reloading our config when any of them
+// change, until the passed in context is cancelled. It returns once the files have been checked for the first time.
+// Which fields changed, or any errors reloading, are logged and the current config is kept if there are errors.
+func (l *Loader) Watch(ctx context.Context, interval time.Duration) {
+	stamps := l.fileStamps()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current := l.fileStamps()
+			if maps.Equal(current, stamps) {
+				continue
+			}
+			stamps = current
+
+			l.reloadAndLog("file change")
+		}
+	}()
+}
+
+// utility struct for detecting when a file has changed
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// returns the modification time and size of each of our config and dotenv files that exists
+func (l *Loader) fileStamps() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for _, file := range slices.Concat(l.files, l.envFiles) {
+		if info, err := os.Stat(file); err == nil {
+			stamps[file] = fileStamp{info.ModTime(), info.Size()}
+		}
+	}
+	return stamps
+}
+
+// returns the sorted snake_case names of the fields whose values differ between the passed in structs, using the
+// same names as our fields, e.g. db_pool_size, for nested structs
+func changedFields(old, updated reflect.Value, prefix string) []string {
+	var changed []string
+
+	for i := 0; i < old.NumField(); i++ {
+		sf := old.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := prefix + fieldKey(sf)
+		if sf.Type.Kind() == reflect.Struct && !isSupportedType(sf.Type) {
+			changed = append(changed, changedFields(old.Field(i), updated.Field(i), name+"_")...)
+		} else if !reflect.DeepEqual(old.Field(i).Interface(), updated.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+
+	slices.Sort(changed)
+	return changed
+}
+
+// returns a deep copy of the passed in value so that changes to the copy, including to its maps and slices, don't
+// affect the original
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,273 @@
+package ezconf
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	type config struct {
+		NumWorkers int `min:"1"`
+		Labels     map[string]string
+		DB         struct {
+			URL      string
+			PoolSize int
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(file, []byte("num_workers = 4\n[labels]\nenv = \"prod\"\n"), 0600)
+
+	c := &config{NumWorkers: 2, Labels: map[string]string{"app": "courier"}}
+	conf := NewLoader(c, "foo", "description", []string{file})
+	conf.SetArgs("-db-pool-size=16")
+
+	// can't reload before loading
+	assert.EqualError(t, conf.Reload(), "config must be loaded before it can be reloaded")
+	assert.Equal(t, c, conf.Config())
+
+	assert.NoError(t, conf.Load())
+	assert.Equal(t, c, conf.Config())
+	assert.Equal(t, 4, c.NumWorkers)
+
+	type change struct {
+		old, updated *config
+		changed      []string
+	}
+	var changes []change
+	conf.OnChange(func(old, updated any, changed []string) {
+		changes = append(changes, change{old.(*config), updated.(*config), changed})
+	})
+
+	// reloading without any changes doesn't notify anything
+	assert.NoError(t, conf.Reload())
+	assert.Len(t, changes, 0)
+
+	// keys removed from files revert to their defaults, and the old config isn't modified
+	os.WriteFile(file, []byte("num_workers = 8\n[db]\nurl = \"postgres://localhost\"\n"), 0600)
+	assert.NoError(t, conf.Reload())
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, c, changes[0].old)
+		assert.Equal(t, []string{"db_url", "labels", "num_workers"}, changes[0].changed)
+
+		updated := changes[0].updated
+		assert.Equal(t, updated, conf.Config())
+		assert.Equal(t, 8, updated.NumWorkers)
+		assert.Equal(t, 16, updated.DB.PoolSize)
+		assert.Equal(t, "postgres://localhost", updated.DB.URL)
+		assert.Equal(t, map[string]string{"app": "courier"}, updated.Labels)
+	}
+	assert.Equal(t, 4, c.NumWorkers)
+	assert.Equal(t, map[string]string{"env": "prod"}, c.Labels)
+	assert.Equal(t, FieldSource{"num_workers", 8, Origin{Kind: OriginFile, Name: file, Line: 1}}, conf.Sources()[2])
+
+	// invalid configs are rejected and the current config kept
+	current := conf.Config()
+	os.WriteFile(file, []byte("num_workers = 0\n"), 0600)
+	assert.EqualError(t, conf.Reload(), "invalid value 0 for num_workers from file "+file+":1, must be at least 1")
+	assert.Equal(t, current, conf.Config())
+	assert.Len(t, changes, 1)
+}
+
+func TestWatch(t *testing.T) {
+	type config struct {
+		NumWorkers int
+	}
+
+	file := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(file, []byte("num_workers = 4\n"), 0600)
+
+	conf := NewLoader(&config{}, "foo", "description", []string{file})
+	conf.SetArgs()
+	assert.NoError(t, conf.Load())
+
+	changed := make(chan []string, 1)
+	conf.OnChange(func(old, updated any, fields []string) { changed <- fields })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conf.Watch(ctx, 10*time.Millisecond)
+
+	// the watcher has read the initial state of our file once it returns
+	os.WriteFile(file, []byte("num_workers = 16\n"), 0600)
+
+	select {
+	case fields := <-changed:
+		assert.Equal(t, []string{"num_workers"}, fields)
+		assert.Equal(t, 16, conf.Config().(*config).NumWorkers)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timed out waiting for reload")
+	}
+}
+
+func TestOnChangeUsesLoader(t *testing.T) {
+	type config struct {
+		NumWorkers int
+	}
+
+	file := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(file, []byte("num_workers = 4\n"), 0600)
+
+	conf := NewLoader(&config{}, "foo", "description", []string{file})
+	conf.SetArgs()
+	assert.NoError(t, conf.Load())
+
+	// functions called on changes can use the loader without deadlocking
+	var holder *Holder[config]
+	conf.OnChange(func(old, updated any, changed []string) {
+		assert.NoError(t, conf.Reload())
+		conf.OnChange(func(old, updated any, changed []string) {})
+
+		var err error
+		holder, err = Hold[config](conf)
+		assert.NoError(t, err)
+	})
+
+	os.WriteFile(file, []byte("num_workers = 8\n"), 0600)
+	assert.NoError(t, conf.Reload())
+	if assert.NotNil(t, holder) {
+		assert.Equal(t, 8, holder.Get().NumWorkers)
+	}
+}
+
+// a buffer which can be written to by one goroutine while being read by another
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestReloadOnSIGHUP(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGHUP can't be sent on windows")
+	}
+
+	type config struct {
+		NumWorkers int `min:"1"`
+	}
+
+	file := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(file, []byte("num_workers = 4\n"), 0600)
+
+	logs := &syncBuffer{}
+	conf := NewLoader(&config{}, "foo", "description", []string{file})
+	conf.SetLogger(slog.New(slog.NewTextHandler(logs, testLogOptions)))
+	conf.SetArgs()
+	assert.NoError(t, conf.Load())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conf.ReloadOnSIGHUP(ctx)
+
+	hup := func() {
+		p, err := os.FindProcess(os.Getpid())
+		assert.NoError(t, err)
+		assert.NoError(t, p.Signal(syscall.SIGHUP))
+	}
+
+	// valid changes are published and logged
+	os.WriteFile(file, []byte("num_workers = 16\n"), 0600)
+	hup()
+	assert.Eventually(t, func() bool { return conf.Config().(*config).NumWorkers == 16 }, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return logs.String() != "" }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "level=INFO msg=\"config reloaded\" trigger=SIGHUP changed=[num_workers]\n", logs.String())
+
+	// invalid changes are logged and the current config kept
+	os.WriteFile(file, []byte("num_workers = 0\n"), 0600)
+	hup()
+	assert.Eventually(t, func() bool { return strings.Count(logs.String(), "\n") == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, logs.String(), `level=ERROR msg="error reloading config, keeping current config" trigger=SIGHUP error="invalid value 0 for num_workers`)
+	assert.Equal(t, 16, conf.Config().(*config).NumWorkers)
+}
+
+func TestReloadRestartRequired(t *testing.T) {
+	type config struct {
+		NumWorkers int    `reload:"false" help:"the number of workers to start"`
+		LogLevel   string `help:"the log level"`
+		Web        struct {
+			Address string
+			Port    int
+		} `reload:"false"`
+	}
+
+	file := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(file, []byte("num_workers = 4\nlog_level = \"info\"\n[web]\nport = 80\n"), 0600)
+
+	output := &bytes.Buffer{}
+	conf := NewLoader(&config{}, "foo", "description", []string{file})
+	conf.SetOutput(output)
+	conf.SetArgs()
+	assert.NoError(t, conf.Load())
+
+	// fields which can't be reloaded are marked in help
+	conf.Usage()
+	assert.Contains(t, output.String(), "the number of workers to start (requires restart)")
+	assert.Contains(t, output.String(), "set value for web_port (requires restart)")
+	assert.NotContains(t, output.String(), "the log level (requires restart)")
+
+	var changes [][]string
+	conf.OnChange(func(old, updated any, changed []string) { changes = append(changes, changed) })
+
+	// changes to those fields aren't applied but everything else is
+	os.WriteFile(file, []byte("num_workers = 8\nlog_level = \"debug\"\n[web]\nport = 8080\n"), 0600)
+	err := conf.Reload()
+	assert.EqualError(t, err, "restart required to change num_workers, web_port")
+
+	var restartErr *RestartRequiredError
+	if assert.ErrorAs(t, err, &restartErr) {
+		assert.Equal(t, []string{"num_workers", "web_port"}, restartErr.Fields)
+	}
+	assert.Equal(t, [][]string{{"log_level"}}, changes)
+
+	c := conf.Config().(*config)
+	assert.Equal(t, 4, c.NumWorkers)
+	assert.Equal(t, "debug", c.LogLevel)
+	assert.Equal(t, 80, c.Web.Port)
+	assert.Equal(t, Origin{Kind: OriginFile, Name: file, Line: 1}, conf.Sources()[1].Origin)
+}
+
+func TestReloadRestartRequiredOrigins(t *testing.T) {
+	type config struct {
+		DB     string `reload:"false"`
+		DBPool int
+	}
+
+	file := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(file, []byte("db = \"postgres://localhost\"\ndb_pool = 7\n"), 0600)
+
+	conf := NewLoader(&config{}, "foo", "description", []string{file})
+	conf.SetArgs()
+	assert.NoError(t, conf.Load())
+
+	// a sibling of a field which can't be reloaded gets the origin of its new value
+	os.WriteFile(file, []byte("db = \"postgres://remote\"\n"), 0600)
+	t.Setenv("FOO_DB_POOL", "8")
+	assert.EqualError(t, conf.Reload(), "restart required to change db")
+
+	assert.Equal(t, []FieldSource{
+		{"db", "postgres://localhost", Origin{Kind: OriginFile, Name: file, Line: 1}},
+		{"db_pool", 8, Origin{Kind: OriginEnv, Name: "FOO_DB_POOL"}},
+	}, conf.Sources())
+}
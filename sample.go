@@ -0,0 +1,232 @@
+package ezconf
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var bareTOMLKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// GenerateTOML renders the passed in configuration struct as a commented TOML document, e.g. to use as an example
+// config file. The struct should contain your defaults. Fields are commented with their `help` tags and any
+// validation rules, nested structs and maps are rendered as tables, values of secret fields are left empty, and
+// passwords in URLs are replaced with xxxxx.
+func GenerateTOML(config any) ([]byte, error) {
+	fields, err := buildFields(config)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &tomlGenerator{fields: fields}
+	g.writeStruct(reflect.Indirect(reflect.ValueOf(config)), "", true, nil)
+	return g.buf.Bytes(), nil
+}
+
+// utility struct for generating a TOML document
+type tomlGenerator struct {
+	buf    bytes.Buffer
+	fields *ezFields
+}
+
+// writes the keys of the passed in struct, followed by the tables for any nested structs, maps or arrays of structs.
+// Fields are looked up by their snake_case names with the passed in prefix, unless they are inside maps and arrays.
+func (g *tomlGenerator) writeStruct(v reflect.Value, prefix string, named bool, table []string) {
+	typ := v.Type()
+	var tables []func()
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		fv := v.Field(i)
+		help := sf.Tag.Get("help")
+		snake := prefix + fieldKey(sf)
+		if f, found := g.fields.fields[snake]; found && named {
+			help = strings.TrimSpace(help + f.rules.describe())
+		}
+		if sf.Tag.Get("secret") == "true" {
+			fv = reflect.Zero(fv.Type())
+		}
+
+		if value, ok := tomlValue(fv); ok {
+			g.writeComment(help)
+			fmt.Fprintf(&g.buf, "%s = %s\n", tomlKeyName(tomlKey(sf)), value)
+			continue
+		}
+
+		// anything else is written as a table after all our keys
+		subTable := append(slices.Clone(table), tomlKey(sf))
+		tables = append(tables, func() { g.writeTable(fv, snake+"_", named, subTable, help) })
+	}
+
+	for _, write := range tables {
+		write()
+	}
+}
+
+// writes the passed in struct or map as a table, or slice of structs as an array of tables
+func (g *tomlGenerator) writeTable(v reflect.Value, prefix string, named bool, table []string, help string) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		g.writeHeader("[%s]", table, help)
+		g.writeStruct(v, prefix, named, table)
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			g.writeHeader("[%s]", table, help)
+			g.writeMap(v, table)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			elem := reflect.Indirect(v.Index(i))
+			if elem.Kind() == reflect.Struct {
+				g.writeHeader("[[%s]]", table, help)
+				g.writeStruct(elem, "", false, table)
+				help = ""
+			}
+		}
+	}
+}
+
+// writes the entries of the passed in map with string keys, followed by tables for any which aren't simple values
+func (g *tomlGenerator) writeMap(v reflect.Value, table []string) {
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	slices.Sort(keys)
+
+	var tables []string
+	for _, k := range keys {
+		if value, ok := tomlValue(v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key()))); ok {
+			fmt.Fprintf(&g.buf, "%s = %s\n", tomlKeyName(k), value)
+		} else {
+			tables = append(tables, k)
+		}
+	}
+	for _, k := range tables {
+		g.writeTable(v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())), "", false, append(slices.Clone(table), k), "")
+	}
+}
+
+func (g *tomlGenerator) writeHeader(format string, table []string, help string) {
+	if g.buf.Len() > 0 {
+		g.buf.WriteString("\n")
+	}
+	g.writeComment(help)
+	names := make([]string, len(table))
+	for i, t := range table {
+		names[i] = tomlKeyName(t)
+	}
+	fmt.Fprintf(&g.buf, format+"\n", strings.Join(names, "."))
+}
+
+func (g *tomlGenerator) writeComment(comment string) {
+	for line := range strings.SplitSeq(comment, "\n") {
+		if line != "" {
+			fmt.Fprintf(&g.buf, "# %s\n", line)
+		}
+	}
+}
+
+// returns the passed in key quoted if it can't be written as a bare key
+func tomlKeyName(key string) string {
+	if bareTOMLKey.MatchString(key) {
+		return key
+	}
+	return tomlQuote(key)
+}
+
+// returns the passed in value formatted as a TOML value, or false if it isn't a simple value or array of them
+func tomlValue(v reflect.Value) (string, bool) {
+	switch x := v.Interface().(type) {
+	case time.Duration:
+		return tomlQuote(x.String()), true
+	case time.Time:
+		return formatDatetime(x), true
+	}
+	if isTextType(v.Type()) {
+		return quoteRedacted(textValue(v)), true
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		f := strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+		if !strings.ContainsAny(f, ".eInN") {
+			f += ".0"
+		}
+		return f, true
+	case reflect.String:
+		return quoteRedacted(v.String()), true
+	case reflect.Slice, reflect.Array:
+		items := make([]string, v.Len())
+		for i := range items {
+			item, ok := tomlValue(v.Index(i))
+			if !ok {
+				return "", false
+			}
+			items[i] = item
+		}
+		return "[" + strings.Join(items, ", ") + "]", true
+	}
+	return "", false
+}
+
+// returns the passed in string as a quoted TOML string, with the passwords of any URLs redacted
+func quoteRedacted(s string) string {
+	s, _ = redactURLs(s)
+	return tomlQuote(s)
+}
+
+// returns the passed in string as a quoted TOML basic string. Unlike strconv.Quote this only uses escapes which are
+// valid in TOML, with control characters which have no short escape written as \uXXXX.
+func tomlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,222 @@
+package ezconf
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// GenerateJSONSchema generates a JSON Schema for config files of the passed in configuration struct, which editors
+// and CI can use to validate TOML, YAML or JSON files. Defaults are taken from the values of the struct, apart from
+// secrets and URLs with passwords, descriptions from `help` tags, and the `min`, `max`, `oneof` and `pattern` tags are
+// included as constraints, apart from oneof for custom types. Required fields aren't required by the schema as they
+// can also be set by env vars and flags.
+func GenerateJSONSchema(config any) ([]byte, error) {
+	fields, err := buildFields(config)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := structSchema(fields, reflect.Indirect(reflect.ValueOf(config)), "", true)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+type jsonSchema map[string]any
+
+// returns the schema for the passed in struct. Fields are looked up by their snake_case names with the passed in
+// prefix, unless they are inside maps and arrays.
+func structSchema(fields *ezFields, v reflect.Value, prefix string, named bool) jsonSchema {
+	typ := v.Type()
+	properties := jsonSchema{}
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		snake := prefix + fieldKey(sf)
+		schema := valueSchema(fields, v.Field(i), snake+"_", named)
+		if schema == nil {
+			continue
+		}
+
+		if help := sf.Tag.Get("help"); help != "" {
+			schema["description"] = help
+		}
+		if sf.Tag.Get("secret") == "true" {
+			delete(schema, "default")
+		}
+		if f, found := fields.fields[snake]; found && named {
+			addRules(schema, f)
+		}
+		properties[tomlKey(sf)] = schema
+	}
+
+	return jsonSchema{"type": "object", "properties": properties, "additionalProperties": false}
+}
+
+// returns the schema for the passed in value, or nil if the value can't be read from a file. Simple values and arrays
+// of them have the value as their default, unless they are inside maps and arrays.
+func valueSchema(fields *ezFields, v reflect.Value, prefix string, named bool) jsonSchema {
+	typ := v.Type()
+
+	var schema jsonSchema
+	switch {
+	case typ == durationType || typ == timeType || isTextType(typ):
+		schema = jsonSchema{"type": "string"}
+	default:
+		switch v.Kind() {
+		case reflect.Bool:
+			schema = jsonSchema{"type": "boolean"}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			schema = jsonSchema{"type": "integer"}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			schema = jsonSchema{"type": "integer", "minimum": 0}
+		case reflect.Float32, reflect.Float64:
+			schema = jsonSchema{"type": "number"}
+		case reflect.String:
+			schema = jsonSchema{"type": "string"}
+		case reflect.Slice, reflect.Array:
+			items := valueSchema(fields, reflect.New(typ.Elem()).Elem(), "", false)
+			if items == nil {
+				return nil
+			}
+			schema = jsonSchema{"type": "array", "items": items}
+		case reflect.Map:
+			values := valueSchema(fields, reflect.New(typ.Elem()).Elem(), "", false)
+			if typ.Key().Kind() != reflect.String || values == nil {
+				return nil
+			}
+			return jsonSchema{"type": "object", "additionalProperties": values}
+		case reflect.Struct:
+			return structSchema(fields, v, prefix, named)
+		case reflect.Pointer:
+			if v.IsNil() {
+				v = reflect.New(typ.Elem())
+			}
+			return valueSchema(fields, v.Elem(), prefix, named)
+		default:
+			return nil
+		}
+	}
+
+	if def, ok := jsonDefault(v); ok && named {
+		schema["default"] = def
+	}
+	return schema
+}
+
+// returns the passed in value as it would be written in a JSON config file, or false if it isn't a simple value or
+// array of them, or contains a URL with a password which shouldn't be published
+func jsonDefault(v reflect.Value) (any, bool) {
+	switch x := v.Interface().(type) {
+	case time.Duration:
+		return x.String(), true
+	case time.Time:
+		return formatDatetime(x), true
+	}
+	if isTextType(v.Type()) {
+		return jsonString(textValue(v))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return jsonString(v.String())
+	case reflect.Slice, reflect.Array:
+		items := make([]any, v.Len())
+		for i := range items {
+			item, ok := jsonDefault(v.Index(i))
+			if !ok {
+				return nil, false
+			}
+			items[i] = item
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+// returns the passed in string as a default, or false if it contains a URL with a password
+func jsonString(s string) (any, bool) {
+	if _, hasPassword := redactURLs(s); hasPassword {
+		return nil, false
+	}
+	return s, true
+}
+
+// adds the validation rules of the passed in field to its schema
+func addRules(schema jsonSchema, f *ezField) {
+	if f.rules == nil {
+		return
+	}
+
+	// bounds of durations and custom types can't be expressed against their string forms
+	var minKey, maxKey string
+	if f.value.Type() != durationType && !isTextType(f.value.Type()) {
+		switch schema["type"] {
+		case "integer", "number":
+			minKey, maxKey = "minimum", "maximum"
+		case "string":
+			minKey, maxKey = "minLength", "maxLength"
+		case "array":
+			minKey, maxKey = "minItems", "maxItems"
+		}
+	}
+	if minKey != "" && f.rules.min != "" {
+		schema[minKey] = jsonNumber(f.rules.min)
+	}
+	if maxKey != "" && f.rules.max != "" {
+		schema[maxKey] = jsonNumber(f.rules.max)
+	}
+
+	// the values of lists are checked individually
+	target := schema
+	if items, isArray := schema["items"].(jsonSchema); isArray {
+		target = items
+	}
+
+	// custom types are compared by their parsed values, which can't be expressed as a list of strings, e.g. info and
+	// INFO are both valid for a slog.Level
+	if f.rules.oneOf != nil && f.rules.oneOfValues == nil {
+		enum := make([]any, len(f.rules.oneOf))
+		for i, o := range f.rules.oneOf {
+			enum[i] = jsonEnumValue(target["type"], o)
+		}
+		target["enum"] = enum
+	}
+	if f.rules.pattern != nil && target["type"] == "string" {
+		target["pattern"] = f.rules.pattern.String()
+	}
+}
+
+// returns the passed in oneof value as a value of the passed in schema type
+func jsonEnumValue(typ any, s string) any {
+	switch typ {
+	case "boolean":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case "integer", "number":
+		return jsonNumber(s)
+	}
+	return s
+}
+
+// returns the passed in string as a JSON number if it is one
+func jsonNumber(s string) any {
+	if _, err := strconv.ParseFloat(s, 64); err != nil || !json.Valid([]byte(s)) {
+		return s
+	}
+	return json.Number(s)
+}
@@ -0,0 +1,57 @@
+package ezconf
+
+import (
+	"net/url"
+	"strings"
+)
+
+// what we show in place of secret values
+const redacted = "********"
+
+// returns whether the passed in field has been tagged as holding a secret with `secret:"true"`
+func isSecret(f *ezField) bool {
+	return f.Tag("secret") == "true"
+}
+
+// redacts the passed in string form of a field's value for display, returning whether anything was redacted. Secret
+// fields have their entire value redacted, and any other values which are URLs have their passwords replaced with xxxxx.
+func redactValue(f *ezField, value string) (string, bool) {
+	if isSecret(f) {
+		if value == "" {
+			return "", false
+		}
+		return redacted, true
+	}
+	return redactURLs(value)
+}
+
+// replaces the passwords of any URLs in the passed in value, which may be a comma separated list, with xxxxx,
+// returning whether anything was redacted
+func redactURLs(value string) (string, bool) {
+	if !strings.Contains(value, "://") {
+		return value, false
+	}
+
+	// value may be a list of URLs so check each part
+	parts := strings.Split(value, ",")
+	found := false
+	for i, p := range parts {
+		u, err := url.Parse(strings.TrimSpace(p))
+		if err == nil && u.User != nil {
+			if _, hasPassword := u.User.Password(); hasPassword {
+				parts[i] = u.Redacted()
+				found = true
+			}
+		}
+	}
+	if !found {
+		return value, false
+	}
+	return strings.Join(parts, ","), true
+}
+
+// returns the current value of the passed in field as a string for display, with any secrets redacted
+func displayValue(f *ezField) string {
+	value, _ := redactValue(f, formatValue(f))
+	return value
+}
@@ -0,0 +1,28 @@
+package ezconf
+
+// Source is a source of configuration values, e.g. a key/value store, which can be added to a Loader with AddSource.
+// The built-in files, environment variables and command line parameters sources are also implementations of it.
+type Source interface {
+	// Values returns the values this source has for any of the passed in snake_case keys, e.g. db_pool_size
+	Values(keys []string) (map[string]SourceValue, error)
+}
+
+// SourceValue is a value read from a Source along with where it was read from. The value can be a string, which
+// is parsed the same way as environment variables and command line parameters, or a value of the field's type.
+type SourceValue struct {
+	Value  any
+	Origin Origin
+}
+
+// priorities of the built-in sources, values from sources with higher priorities override those with lower ones
+const (
+	PriorityFiles = 100
+	PriorityEnv   = 200
+	PriorityFlags = 300
+)
+
+// utility struct for a source along with its priority
+type prioritizedSource struct {
+	source   Source
+	priority int
+}
@@ -0,0 +1,75 @@
+package ezconf
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// a source which returns fixed values, like a key/value store would
+type testSource struct {
+	values map[string]any
+	err    error
+}
+
+func (s *testSource) Values(keys []string) (map[string]SourceValue, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	values := make(map[string]SourceValue)
+	for _, k := range keys {
+		if v, found := s.values[k]; found {
+			values[k] = SourceValue{v, Origin{Kind: "kv", Name: k}}
+		}
+	}
+	return values, nil
+}
+
+func TestAddSource(t *testing.T) {
+	type config struct {
+		NumWorkers int
+		LogLevel   slog.Level
+		Timeout    time.Duration
+		DB         struct {
+			URL      string
+			PoolSize int
+		}
+	}
+
+	kv := &testSource{values: map[string]any{"num_workers": "12", "db_pool_size": "8", "timeout": 5 * time.Second, "log_level": "error"}}
+	override := &testSource{values: map[string]any{"log_level": slog.LevelWarn}}
+
+	c := &config{}
+	conf := NewLoader(c, "foo", "description", []string{"testdata/workers.toml"})
+	conf.AddSource(kv, PriorityFiles+50)
+	conf.AddSource(override, PriorityFlags)
+	conf.SetArgs("-log-level=debug")
+	t.Setenv("FOO_DB_POOL_SIZE", "24")
+
+	// our source overrides files but not env vars, and the source added at flag priority overrides flags
+	err := conf.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldSource{
+		{"db_pool_size", 24, Origin{Kind: OriginEnv, Name: "FOO_DB_POOL_SIZE"}},
+		{"db_url", "", Origin{Kind: OriginDefault}},
+		{"log_level", slog.LevelWarn, Origin{Kind: "kv", Name: "log_level"}},
+		{"num_workers", 12, Origin{Kind: "kv", Name: "num_workers"}},
+		{"timeout", 5 * time.Second, Origin{Kind: "kv", Name: "timeout"}},
+	}, conf.Sources())
+	assert.Equal(t, "kv num_workers", conf.Sources()[3].Origin.String())
+
+	// values which aren't strings must match the type of their field
+	conf = NewLoader(&config{}, "foo", "description", nil)
+	conf.AddSource(&testSource{values: map[string]any{"num_workers": 12.5}}, PriorityFiles)
+	conf.SetArgs()
+	assert.EqualError(t, conf.Load(), "invalid value '12.5' for num_workers, must be a string or int")
+
+	// errors from sources are returned
+	conf = NewLoader(&config{}, "foo", "description", nil)
+	conf.AddSource(&testSource{err: errors.New("kv store unavailable")}, PriorityFiles)
+	conf.SetArgs()
+	assert.EqualError(t, conf.Load(), "kv store unavailable")
+}
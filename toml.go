@@ -1,63 +1,290 @@
 package ezconf
 
 import (
-	"bytes"
-	"fmt"
-	"io"
-	"os"
+	"cmp"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/naoina/toml"
+	"github.com/naoina/toml/ast"
 )
 
-// Iterates the list of files, parsing the first that is found and loading the
-// result into the passed in struct pointer. If no files are passed in or
-// no files are found, this is a noop.
-func parseTOMLFiles(config any, files []string, debug bool) error {
-	// search through our list of files, stopping when we find one
-	for i, file := range files {
-		toml, err := os.ReadFile(file)
-		if err != nil {
-			// not finding a file is ok, we just move on
-			if os.IsNotExist(err) {
-				if debug {
-					fmt.Printf("CONF: Skipping missing TOML file: %s\n", file)
-				}
+var durationType = reflect.TypeOf(time.Duration(0))
+var timeType = reflect.TypeOf(time.Time{})
+
+// decodes the passed in parsed TOML table into our config struct, returning any values for custom types which
+// can only be set from strings, and the line of each key that maps to a field
+func decodeTable(table *ast.Table, config any) (map[string]string, map[string]int, error) {
+	values := make(map[string]string)
+	lines := make(map[string]int)
+	err := walkTOML(table, reflect.TypeOf(config), func(key string, kv *ast.KeyValue, typ reflect.Type) error {
+		if key != "" {
+			lines[key] = kv.Line
+		}
+
+		// naoina/toml can't decode types which only implement flag.Value so we remove them to be set later
+		if key != "" && isFlagValueOnly(typ) {
+			if str, isString := kv.Value.(*ast.String); isString {
+				values[key] = str.Value
+				kv.Value = nil
+				return nil
+			}
+		}
+		return convertStrings(kv, typ)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = newTOMLConfig().UnmarshalTable(table, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return values, lines, nil
+}
+
+// walks the passed in TOML table alongside the type it will be decoded into, calling fn for every key/value whose
+// destination type is known. The key passed to fn is the snake_case name of the field, e.g. db_pool_size, or empty
+// if the value is inside a map or array of tables. fn can remove a key/value from the table by setting its value to nil.
+func walkTOML(table *ast.Table, typ reflect.Type, fn func(key string, kv *ast.KeyValue, typ reflect.Type) error) error {
+	return walkTOMLTable(table, typ, "", true, fn)
+}
+
+func walkTOMLTable(table *ast.Table, typ reflect.Type, prefix string, named bool, fn func(key string, kv *ast.KeyValue, typ reflect.Type) error) error {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	for key, fieldAst := range table.Fields {
+		var fieldType reflect.Type
+		var fieldName string
+
+		switch typ.Kind() {
+		case reflect.Struct:
+			sf, found := findTOMLField(typ, key)
+			if !found {
 				continue
 			}
-			return err
+			fieldType = sf.Type
+			if named {
+				fieldName = prefix + fieldKey(sf)
+			}
+		case reflect.Map:
+			fieldType = typ.Elem()
+		default:
+			continue
 		}
-		if debug {
-			fmt.Printf("CONF: Parsing TOML file: %s\n", file)
+
+		switch av := fieldAst.(type) {
+		case *ast.KeyValue:
+			if err := fn(fieldName, av, fieldType); err != nil {
+				return err
+			}
+			if av.Value == nil {
+				delete(table.Fields, key)
+			}
+		case *ast.Table:
+			if err := walkTOMLTable(av, fieldType, fieldName+"_", fieldName != "", fn); err != nil {
+				return err
+			}
+		case []*ast.Table:
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Slice {
+				for _, t := range av {
+					if err := walkTOMLTable(t, fieldType.Elem(), "", false, fn); err != nil {
+						return err
+					}
+				}
+			}
 		}
-		decoder := newDecoder(bytes.NewReader(toml))
-		err = decoder.Decode(config)
+	}
+	return nil
+}
 
-		// if we can't parse this file as TOML, that's a nogo
-		if err != nil {
-			return err
+// utility struct for a key in a file which doesn't map to any field of our config
+type unknownKey struct {
+	key        string // the full key, e.g. db.pool_sise
+	line       int
+	suggestion string // the full key that might have been intended, if any
+}
+
+// finds every key in the passed in TOML table which doesn't map to a field of the type it will be decoded into,
+// ordered by line, along with a suggestion for the key that might have been intended
+func findUnknownKeys(table *ast.Table, typ reflect.Type) []unknownKey {
+	unknown := findUnknownTableKeys(table, typ, "")
+	slices.SortFunc(unknown, func(a, b unknownKey) int { return cmp.Compare(a.line, b.line) })
+	return unknown
+}
+
+func findUnknownTableKeys(table *ast.Table, typ reflect.Type, prefix string) []unknownKey {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	var unknown []unknownKey
+	for key, fieldAst := range table.Fields {
+		var fieldType reflect.Type
+
+		switch typ.Kind() {
+		case reflect.Struct:
+			sf, found := findTOMLField(typ, key)
+			if !found {
+				u := unknownKey{key: prefix + key, line: astLine(fieldAst)}
+				if suggestion := suggest(key, tomlKeys(typ)); suggestion != "" {
+					u.suggestion = prefix + suggestion
+				}
+				unknown = append(unknown, u)
+				continue
+			}
+			fieldType = sf.Type
+		case reflect.Map:
+			fieldType = typ.Elem()
+		default:
+			continue
 		}
-		if debug {
-			for i = i + 1; i < len(files); i++ {
-				fmt.Printf("CONF: Previous file found, skipping TOML file: %s\n", files[i])
+
+		switch av := fieldAst.(type) {
+		case *ast.Table:
+			unknown = append(unknown, findUnknownTableKeys(av, fieldType, prefix+key+".")...)
+		case []*ast.Table:
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
 			}
+			if fieldType.Kind() == reflect.Slice {
+				for _, t := range av {
+					unknown = append(unknown, findUnknownTableKeys(t, fieldType.Elem(), prefix+key+".")...)
+				}
+			}
+		}
+	}
+	return unknown
+}
+
+// returns the TOML keys of all the fields of the passed in struct type
+func tomlKeys(typ reflect.Type) []string {
+	keys := make([]string, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		if sf := typ.Field(i); sf.PkgPath == "" {
+			keys = append(keys, tomlKey(sf))
+		}
+	}
+	return keys
+}
+
+// returns the TOML key of the passed in struct field, which is its toml tag if it has one
+func tomlKey(sf reflect.StructField) string {
+	if tag, _, _ := strings.Cut(sf.Tag.Get("toml"), ","); tag != "" {
+		return tag
+	}
+	return fieldKey(sf)
+}
+
+// returns the line of the passed in TOML key/value, table or array of tables
+func astLine(v any) int {
+	switch av := v.(type) {
+	case *ast.KeyValue:
+		return av.Line
+	case *ast.Table:
+		return av.Line
+	case []*ast.Table:
+		if len(av) > 0 {
+			return av[0].Line
+		}
+	}
+	return 0
+}
+
+// returns the snake_case name of the passed in struct field
+func fieldKey(sf reflect.StructField) string {
+	if name := sf.Tag.Get("name"); name != "" {
+		return name
+	}
+	return CamelToSnake(sf.Name)
+}
+
+// returns whether the passed in type can only be set from a string using its flag.Value implementation
+func isFlagValueOnly(typ reflect.Type) bool {
+	ptr := reflect.PointerTo(typ)
+	return ptr.Implements(flagValueType) && !ptr.Implements(textUnmarshalerType)
+}
+
+// finds the struct field that the passed in TOML key will be decoded into, using the same rules as the decoder
+func findTOMLField(typ reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		tag, _, _ := strings.Cut(sf.Tag.Get("toml"), ",")
+		if tag == key || (tag == "" && camelNormalizer(typ, sf.Name) == camelNormalizer(typ, key)) {
+			return sf, true
 		}
+	}
+	return reflect.StructField{}, false
+}
 
-		// we break at the first file we find
-		break
+// naoina/toml has no support for time.Duration, and only decodes TOML datetimes into time.Time, so we replace any
+// strings destined for those types, e.g. "1m30s", with values it can decode
+func convertStrings(kv *ast.KeyValue, typ reflect.Type) error {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	var err error
+	if convert, found := stringConverters[typ]; found {
+		kv.Value, err = convertString(kv.Value, convert)
+	} else if arr, isArray := kv.Value.(*ast.Array); isArray && typ.Kind() == reflect.Slice {
+		if convert, found := stringConverters[typ.Elem()]; found {
+			for i := range arr.Value {
+				if arr.Value[i], err = convertString(arr.Value[i], convert); err != nil {
+					break
+				}
+			}
+		}
 	}
 
+	if err != nil {
+		return &toml.LineError{Line: kv.Line, StructField: kv.Key, Err: err}
+	}
 	return nil
 }
 
-// We build our own decoder that uses our own CamelToSnake and is a bit stricter with
+func convertString(v ast.Value, convert func(*ast.String) (ast.Value, error)) (ast.Value, error) {
+	if str, isString := v.(*ast.String); isString {
+		return convert(str)
+	}
+	return v, nil
+}
+
+var stringConverters = map[reflect.Type]func(*ast.String) (ast.Value, error){
+	durationType: func(s *ast.String) (ast.Value, error) {
+		d, err := time.ParseDuration(s.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Integer{Position: s.Position, Value: strconv.FormatInt(int64(d), 10), Data: s.Data}, nil
+	},
+	timeType: func(s *ast.String) (ast.Value, error) {
+		t, err := parseDatetime(s.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Datetime{Position: s.Position, Value: t.Format(time.RFC3339Nano), Data: s.Data}, nil
+	},
+}
+
+// We build our own decoder config that uses our own CamelToSnake and is a bit stricter with
 // matching of fields in our TOML file. (they must match CamelToSnake)
-func newDecoder(r io.Reader) *toml.Decoder {
-	tomlConfig := &toml.Config{
+func newTOMLConfig() *toml.Config {
+	return &toml.Config{
 		NormFieldName: camelNormalizer,
 		FieldToKey:    camelKey,
 	}
-	return tomlConfig.NewDecoder(r)
 }
 
 // resolveNameTag checks if a struct field has a `name` tag and returns it if present.
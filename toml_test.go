@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/naoina/toml"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -12,19 +13,31 @@ type simpleStruct struct {
 	MyInt      int
 	MyBool     bool
 	MyDatetime time.Time
+	MyDuration time.Duration
 	MyLogLevel slog.Level
 
-	MyInts    []int
-	MyStrings []string
+	MyInts      []int
+	MyStrings   []string
+	MyDurations []time.Duration
 
 	Nested struct {
-		NestedInt int
+		NestedInt      int
+		NestedDuration time.Duration
 	}
 }
 
+// parses and decodes the passed in TOML document into the passed in config
+func decodeTOML(data []byte, config any) (map[string]string, map[string]int, error) {
+	table, err := toml.Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decodeTable(table, config)
+}
+
 func TestParsing(t *testing.T) {
 	s := &simpleStruct{}
-	err := parseTOMLFiles(s, []string{"testdata/notthere.toml", "testdata/simple.toml", "testdata/skipped.toml"}, true)
+	_, keys, err := parseFiles(s, []string{"testdata/notthere.toml", "testdata/simple.toml", "testdata/skipped.toml"}, false, false, slog.New(slog.DiscardHandler))
 
 	assert.NoError(t, err)
 	assert.Equal(t, 32, s.MyInt)
@@ -33,4 +46,31 @@ func TestParsing(t *testing.T) {
 	assert.Equal(t, []string{"foo", "bar"}, s.MyStrings)
 	assert.Equal(t, 64, s.Nested.NestedInt)
 	assert.Equal(t, time.Date(2018, 4, 3, 5, 30, 0, 0, time.UTC), s.MyDatetime)
+	assert.Equal(t, 15*time.Second, s.MyDuration)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Minute, 5}, s.MyDurations)
+	assert.Equal(t, 90*time.Second, s.Nested.NestedDuration)
+	assert.Equal(t, Origin{Kind: OriginFile, Name: "testdata/simple.toml", Line: 2}, keys["my_int"])
+	assert.Equal(t, Origin{Kind: OriginFile, Name: "testdata/simple.toml", Line: 13}, keys["nested_nested_int"])
+
+	// invalid durations are reported with their line
+	_, _, err = decodeTOML([]byte("my_int = 5\nmy_duration = \"15\""), &simpleStruct{})
+	assert.EqualError(t, err, "line 2: (my_duration) time: missing unit in duration \"15\"")
+}
+
+func TestDecodeCustomTypes(t *testing.T) {
+	type config struct {
+		Format logFormat
+		Hosts  hostList
+		Nested struct {
+			Hosts hostList
+		}
+	}
+
+	// types implementing encoding.TextUnmarshaler are decoded directly, flag.Value only types are returned
+	c := &config{}
+	values, _, err := decodeTOML([]byte("format = \"json\"\nhosts = \"a.com;b.com\"\n[nested]\nhosts = \"c.com\""), c)
+	assert.NoError(t, err)
+	assert.Equal(t, formatJSON, c.Format)
+	assert.Nil(t, c.Hosts)
+	assert.Equal(t, map[string]string{"hosts": "a.com;b.com", "nested_hosts": "c.com"}, values)
 }
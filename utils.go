@@ -1,6 +1,7 @@
 package ezconf
 
 import (
+	"sort"
 	"strings"
 	"time"
 	"unicode"
@@ -72,6 +73,71 @@ var timeFormats = []string{
 	"2006-01-02T15:04:05.999999999",
 }
 
+// parses a datetime in any of the formats supported by TOML, i.e. a date, a time or a date and time
+func parseDatetime(value string) (time.Time, error) {
+	switch {
+	case !strings.Contains(value, ":"):
+		return time.Parse("2006-01-02", value)
+	case !strings.Contains(value, "-"):
+		return time.Parse("15:04:05.999999999", value)
+	}
+
+	var t time.Time
+	var err error
+	for _, format := range timeFormats {
+		t, err = time.Parse(format, value)
+		if err == nil {
+			break
+		}
+	}
+	return t, err
+}
+
 func formatDatetime(t time.Time) string {
 	return t.Format(timeFormats[0])
 }
+
+// returns the keys of the passed in map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// returns the candidate closest to the passed in string, e.g. num_workers for num_worker, or an empty string if none
+// are close enough to be a likely typo
+func suggest(s string, candidates []string) string {
+	best, bestDistance := "", len(s)/3+2
+	for _, c := range candidates {
+		if d := levenshtein(s, c); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// returns the number of single character edits needed to change a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
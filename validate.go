@@ -0,0 +1,210 @@
+package ezconf
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validator can be implemented by your configuration struct, or any struct nested within it, to check rules that
+// can't be expressed with tags, e.g. that one field must be set if another is. Validate is called after all sources
+// have been loaded, with nested structs validated before the structs that contain them.
+type Validator interface {
+	Validate() error
+}
+
+// calls Validate on the passed in struct value, and on any nested structs, which implement Validator
+func runValidators(v reflect.Value) []error {
+	var errs []error
+
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).IsExported() && v.Field(i).Kind() == reflect.Struct {
+			errs = append(errs, runValidators(v.Field(i))...)
+		}
+	}
+
+	var validator Validator
+	if v.CanAddr() {
+		validator, _ = v.Addr().Interface().(Validator)
+	} else {
+		validator, _ = v.Interface().(Validator)
+	}
+	if validator != nil {
+		if err := validator.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// validation rules for a field, read from its min, max, oneof and pattern tags
+type ezRules struct {
+	min         string
+	max         string
+	oneOf       []string
+	oneOfValues []any // oneOf parsed as values of custom types, e.g. slog.Level
+	pattern     *regexp.Regexp
+}
+
+// parses the validation rules for the passed in field, returning nil if it has none
+func parseRules(f *ezField) (*ezRules, error) {
+	rules := &ezRules{min: f.Tag("min"), max: f.Tag("max")}
+
+	// check our bounds can be compared against this field by comparing them to its default value
+	for _, bound := range []string{rules.min, rules.max} {
+		if bound != "" {
+			if _, err := compareBound(f.value, bound); err != nil {
+				return nil, fmt.Errorf("invalid min or max tag for field %s: %w", f.path, err)
+			}
+		}
+	}
+
+	if oneOf := f.Tag("oneof"); oneOf != "" {
+		rules.oneOf = strings.Split(oneOf, ",")
+		for i := range rules.oneOf {
+			rules.oneOf[i] = strings.TrimSpace(rules.oneOf[i])
+		}
+
+		// custom types are compared by value so that e.g. info matches a slog.Level whose string form is INFO
+		if isTextType(f.value.Type()) {
+			for _, o := range rules.oneOf {
+				v, err := parseText(f.value.Type(), o)
+				if err != nil {
+					return nil, fmt.Errorf("invalid oneof tag for field %s: %w", f.path, err)
+				}
+				rules.oneOfValues = append(rules.oneOfValues, v.Interface())
+			}
+		}
+	}
+
+	if pattern := f.Tag("pattern"); pattern != "" {
+		var err error
+		rules.pattern, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern tag for field %s: %w", f.path, err)
+		}
+	}
+
+	if rules.min == "" && rules.max == "" && rules.oneOf == nil && rules.pattern == nil {
+		return nil, nil
+	}
+	return rules, nil
+}
+
+// returns a description of the rules for use in help, e.g. " (min 1, max 512)"
+func (r *ezRules) describe() string {
+	if r == nil {
+		return ""
+	}
+
+	parts := make([]string, 0, 4)
+	if r.min != "" {
+		parts = append(parts, "min "+r.min)
+	}
+	if r.max != "" {
+		parts = append(parts, "max "+r.max)
+	}
+	if r.oneOf != nil {
+		parts = append(parts, "one of "+strings.Join(r.oneOf, "|"))
+	}
+	if r.pattern != nil {
+		parts = append(parts, "matching "+r.pattern.String())
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// checks the current value of the passed in field against its rules, returning an error for each rule broken
+func validateField(key string, f *ezField, origin Origin) []error {
+	if f.rules == nil {
+		return nil
+	}
+
+	var errs []error
+	invalid := func(msg string, args ...any) {
+		errs = append(errs, fmt.Errorf("invalid value %s for %s from %s, %s", displayValue(f), key, origin, fmt.Sprintf(msg, args...)))
+	}
+
+	if f.rules.min != "" {
+		if c, _ := compareBound(f.value, f.rules.min); c < 0 {
+			invalid("must be at least %s", f.rules.min)
+		}
+	}
+	if f.rules.max != "" {
+		if c, _ := compareBound(f.value, f.rules.max); c > 0 {
+			invalid("must be at most %s", f.rules.max)
+		}
+	}
+
+	// string lists have each of their values checked, everything else is checked using its string form
+	values := []string{formatValue(f)}
+	if strs, isStrings := f.Value().([]string); isStrings {
+		values = strs
+	}
+
+	if f.rules.oneOfValues != nil {
+		if !slices.ContainsFunc(f.rules.oneOfValues, func(o any) bool { return reflect.DeepEqual(o, f.Value()) }) {
+			invalid("must be one of %s", strings.Join(f.rules.oneOf, ", "))
+		}
+	} else {
+		for _, v := range values {
+			if f.rules.oneOf != nil && !slices.Contains(f.rules.oneOf, v) {
+				invalid("must be one of %s", strings.Join(f.rules.oneOf, ", "))
+				break
+			}
+		}
+	}
+	for _, v := range values {
+		if f.rules.pattern != nil && !f.rules.pattern.MatchString(v) {
+			invalid("must match %s", f.rules.pattern)
+			break
+		}
+	}
+
+	return errs
+}
+
+// compares the passed in value against a min or max bound, returning -1, 0 or 1. Numbers and durations are compared
+// by value, strings and slices by their length.
+func compareBound(v reflect.Value, bound string) (int, error) {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(bound)
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(v.Int(), int64(d)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(bound, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(v.Int(), i), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(bound, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(v.Uint(), i), nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(bound, 64)
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(v.Float(), f), nil
+	case reflect.String, reflect.Slice:
+		i, err := strconv.Atoi(bound)
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(v.Len(), i), nil
+	}
+	return 0, fmt.Errorf("not supported for type %s", v.Type())
+}
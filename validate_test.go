@@ -0,0 +1,136 @@
+package ezconf
+
+import (
+	"errors"
+	"flag"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidation(t *testing.T) {
+	type config struct {
+		NumWorkers int           `min:"1" max:"512" help:"the number of workers"`
+		Timeout    time.Duration `min:"1s" max:"1m"`
+		Ratio      float64       `min:"0" max:"1"`
+		LogLevel   string        `oneof:"debug, info, warn"`
+		Bucket     string        `pattern:"^s3://"`
+		Hosts      []string      `max:"2" pattern:"\\.com$"`
+	}
+
+	fields := toFields(t, &config{})
+	assert.Nil(t, fields.fields["hosts"].rules.oneOf)
+
+	// rules are shown in help
+	fs := buildFlags("foo", "description", fields, flag.ContinueOnError)
+	assert.Equal(t, "the number of workers (min 1, max 512)", fs.Lookup("num-workers").Usage)
+	assert.Equal(t, "set value for log_level (one of debug|info|warn)", fs.Lookup("log-level").Usage)
+	assert.Equal(t, "set value for bucket (matching ^s3://)", fs.Lookup("bucket").Usage)
+
+	// valid values pass
+	c := &config{NumWorkers: 32, Timeout: 10 * time.Second, Ratio: 0.5, LogLevel: "info", Bucket: "s3://foo", Hosts: []string{"a.com"}}
+	conf := NewLoader(c, "foo", "description", nil)
+	conf.SetArgs("-num-workers=512", "-timeout=1m")
+	assert.NoError(t, conf.Load())
+
+	// invalid values are all reported with where they came from
+	conf.SetArgs("-num-workers=1024", "-timeout=500ms", "-hosts=a.com,b.org,c.com")
+	t.Setenv("FOO_LOG_LEVEL", "error")
+	c.Bucket = "gs://foo"
+	c.Ratio = 1.5
+
+	err := conf.Load()
+	assert.EqualError(t, err, "invalid value gs://foo for bucket from default, must match ^s3://\n"+
+		"invalid value a.com,b.org,c.com for hosts from flag -hosts, must be at most 2\n"+
+		"invalid value a.com,b.org,c.com for hosts from flag -hosts, must match \\.com$\n"+
+		"invalid value error for log_level from env FOO_LOG_LEVEL, must be one of debug, info, warn\n"+
+		"invalid value 1024 for num_workers from flag -num-workers, must be at most 512\n"+
+		"invalid value 1.5 for ratio from default, must be at most 1\n"+
+		"invalid value 500ms for timeout from flag -timeout, must be at least 1s")
+
+	// invalid tags are errors
+	type badBound struct {
+		Timeout time.Duration `min:"10"`
+	}
+	_, err = buildFields(&badBound{})
+	assert.EqualError(t, err, `invalid min or max tag for field Timeout: time: missing unit in duration "10"`)
+
+	type badType struct {
+		Start time.Time `max:"10"`
+	}
+	_, err = buildFields(&badType{})
+	assert.EqualError(t, err, `invalid min or max tag for field Start: not supported for type time.Time`)
+
+	type badPattern struct {
+		Bucket string `pattern:"[a-"`
+	}
+	_, err = buildFields(&badPattern{})
+	assert.EqualError(t, err, "invalid pattern tag for field Bucket: error parsing regexp: missing closing ]: `[a-`")
+}
+
+func TestOneOfCustomTypes(t *testing.T) {
+	type config struct {
+		LogLevel slog.Level `oneof:"debug,info,warn"`
+	}
+
+	// values are compared with the oneof values parsed as the field's type, rather than as strings
+	for _, level := range []string{"info", "INFO", "debug", "WARN"} {
+		conf := NewLoader(&config{}, "foo", "description", nil)
+		conf.SetArgs("-log-level=" + level)
+		assert.NoError(t, conf.Load(), "unexpected error for level %s", level)
+	}
+
+	c := &config{}
+	conf := NewLoader(c, "foo", "description", nil)
+	conf.SetArgs("-log-level=error")
+	assert.EqualError(t, conf.Load(), "invalid value ERROR for log_level from flag -log-level, must be one of debug, info, warn")
+
+	// oneof values must be parseable as the field's type
+	type badOneOf struct {
+		LogLevel slog.Level `oneof:"info,loud"`
+	}
+	_, err := buildFields(&badOneOf{})
+	assert.EqualError(t, err, `invalid oneof tag for field LogLevel: slog: level string "loud": unknown name`)
+}
+
+type s3Config struct {
+	Enabled bool
+	Bucket  string
+}
+
+func (c *s3Config) Validate() error {
+	if c.Enabled && c.Bucket == "" {
+		return errors.New("bucket must be set if S3 is enabled")
+	}
+	return nil
+}
+
+type validatedConfig struct {
+	NumWorkers int `min:"1"`
+	MaxWorkers int
+	S3         s3Config
+}
+
+func (c validatedConfig) Validate() error {
+	if c.NumWorkers > c.MaxWorkers {
+		return errors.New("num_workers can't be greater than max_workers")
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	c := &validatedConfig{NumWorkers: 4, MaxWorkers: 8}
+	conf := NewLoader(c, "foo", "description", nil)
+	conf.SetArgs("-s3-enabled", "-s3-bucket=uploads")
+	assert.NoError(t, conf.Load())
+
+	// nested structs are validated first, and errors are reported along with those from tags
+	conf.SetArgs("-s3-enabled", "-s3-bucket=", "-num-workers=0")
+	c.MaxWorkers = -1
+	err := conf.Load()
+	assert.EqualError(t, err, "invalid value 0 for num_workers from flag -num-workers, must be at least 1\n"+
+		"bucket must be set if S3 is enabled\n"+
+		"num_workers can't be greater than max_workers")
+}
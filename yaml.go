@@ -0,0 +1,140 @@
+package ezconf
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/naoina/toml/ast"
+	"gopkg.in/yaml.v3"
+)
+
+// parses the passed in YAML document into a TOML table, mappings becoming tables and sequences of mappings becoming
+// arrays of tables, so that it can be decoded the same way as a TOML file
+func parseYAML(data []byte) (*ast.Table, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	// an empty document has no content
+	if len(doc.Content) == 0 {
+		return &ast.Table{Fields: make(map[string]any)}, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: YAML document must be a mapping", root.Line)
+	}
+	return yamlTable(root, ast.TableTypeNormal)
+}
+
+func yamlTable(node *yaml.Node, typ ast.TableType) (*ast.Table, error) {
+	table := &ast.Table{Line: node.Line, Type: typ, Fields: make(map[string]any, len(node.Content)/2)}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolveAlias(node.Content[i+1])
+
+		switch {
+		case value.Kind == yaml.MappingNode:
+			sub, err := yamlTable(value, ast.TableTypeNormal)
+			if err != nil {
+				return nil, err
+			}
+			sub.Line = key.Line
+			table.Fields[key.Value] = sub
+
+		case isTableSequence(value):
+			tables := make([]*ast.Table, len(value.Content))
+			for j, item := range value.Content {
+				sub, err := yamlTable(resolveAlias(item), ast.TableTypeArray)
+				if err != nil {
+					return nil, err
+				}
+				tables[j] = sub
+			}
+			table.Fields[key.Value] = tables
+
+		case value.Tag == "!!null":
+			// null values are treated as not being set
+			continue
+
+		default:
+			v, err := yamlValue(value)
+			if err != nil {
+				return nil, err
+			}
+			table.Fields[key.Value] = &ast.KeyValue{Key: key.Value, Value: v, Line: key.Line}
+		}
+	}
+	return table, nil
+}
+
+// converts a YAML scalar or sequence of scalars into the equivalent TOML value
+func yamlValue(node *yaml.Node) (ast.Value, error) {
+	node = resolveAlias(node)
+	data := []rune(node.Value)
+
+	if node.Kind == yaml.SequenceNode {
+		arr := &ast.Array{Value: make([]ast.Value, len(node.Content))}
+		for i, item := range node.Content {
+			v, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			arr.Value[i] = v
+		}
+		return arr, nil
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("line %d: unsupported YAML value", node.Line)
+	}
+
+	var err error
+	switch node.ShortTag() {
+	case "!!int":
+		var i int64
+		if err = node.Decode(&i); err == nil {
+			return &ast.Integer{Value: strconv.FormatInt(i, 10), Data: data}, nil
+		}
+	case "!!float":
+		var f float64
+		if err = node.Decode(&f); err == nil {
+			return &ast.Float{Value: strconv.FormatFloat(f, 'g', -1, 64), Data: data}, nil
+		}
+	case "!!bool":
+		var b bool
+		if err = node.Decode(&b); err == nil {
+			return &ast.Boolean{Value: strconv.FormatBool(b), Data: data}, nil
+		}
+	case "!!timestamp":
+		var t time.Time
+		if err = node.Decode(&t); err == nil {
+			return &ast.Datetime{Value: t.Format(time.RFC3339Nano), Data: data}, nil
+		}
+	default:
+		return &ast.String{Value: node.Value, Data: data}, nil
+	}
+	return nil, err
+}
+
+// returns whether the passed in node is a sequence of mappings, which we treat as an array of tables
+func isTableSequence(node *yaml.Node) bool {
+	if node.Kind != yaml.SequenceNode || len(node.Content) == 0 {
+		return false
+	}
+	for _, item := range node.Content {
+		if resolveAlias(item).Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return true
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
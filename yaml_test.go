@@ -0,0 +1,72 @@
+package ezconf
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseYAML(t *testing.T) {
+	type config struct {
+		MyInt       int
+		MyBool      bool
+		MyDatetime  time.Time
+		MyDuration  time.Duration
+		MyLogLevel  slog.Level
+		MyInts      []int
+		MyStrings   []string
+		MyDurations []time.Duration
+		Nested      struct {
+			NestedInt      int
+			NestedDuration time.Duration
+		}
+		MyFloat    float64
+		OpenSearch string `name:"opensearch"`
+		Skipped    string
+		Labels     map[string]string
+		Servers    []struct {
+			Host string
+			Port int
+		}
+	}
+
+	c := &config{Skipped: "default"}
+	_, keys, err := parseFiles(c, []string{"testdata/missing.yaml", "testdata/simple.yaml"}, false, false, slog.New(slog.DiscardHandler))
+	assert.NoError(t, err)
+	assert.Equal(t, 32, c.MyInt)
+	assert.True(t, c.MyBool)
+	assert.Equal(t, time.Date(2018, 4, 3, 5, 30, 0, 0, time.UTC), c.MyDatetime)
+	assert.Equal(t, 15*time.Second, c.MyDuration)
+	assert.Equal(t, slog.LevelInfo, c.MyLogLevel)
+	assert.Equal(t, []int{10, 20, 30}, c.MyInts)
+	assert.Equal(t, []string{"foo", "bar"}, c.MyStrings)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Minute, 5}, c.MyDurations)
+	assert.Equal(t, 1.5, c.MyFloat)
+	assert.Equal(t, "http://from-yaml", c.OpenSearch)
+	assert.Equal(t, "default", c.Skipped)
+	assert.Equal(t, 64, c.Nested.NestedInt)
+	assert.Equal(t, 90*time.Second, c.Nested.NestedDuration)
+	assert.Equal(t, map[string]string{"env": "prod"}, c.Labels)
+	assert.Len(t, c.Servers, 2)
+	assert.Equal(t, "b.com", c.Servers[1].Host)
+	assert.Equal(t, 443, c.Servers[1].Port)
+	assert.Equal(t, Origin{Kind: OriginFile, Name: "testdata/simple.yaml", Line: 2}, keys["my_int"])
+	assert.Equal(t, Origin{Kind: OriginFile, Name: "testdata/simple.yaml", Line: 17}, keys["nested_nested_int"])
+
+	// YAML files can be merged with TOML files
+	c = &config{}
+	_, _, err = parseFiles(c, []string{"testdata/simple.yaml", "testdata/fields.toml"}, true, false, slog.New(slog.DiscardHandler))
+	assert.NoError(t, err)
+	assert.Equal(t, 96, c.MyInt)
+	assert.Equal(t, 64, c.Nested.NestedInt)
+
+	// empty documents are fine, but documents must be mappings
+	_, err = parseYAML([]byte(""))
+	assert.NoError(t, err)
+	_, err = parseYAML([]byte("- foo\n- bar"))
+	assert.EqualError(t, err, "line 1: YAML document must be a mapping")
+	_, err = parseYAML([]byte("foo: [bar"))
+	assert.Error(t, err)
+}